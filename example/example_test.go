@@ -41,6 +41,9 @@ var queryTests = []struct {
 		Errors: []problem.ParamError{{
 			Field:  "ids",
 			Reason: "an error occured via UnmarshalText: strconv.Atoi: parsing \"foo\": invalid syntax",
+			In:     "form",
+			Code:   "invalid",
+			Params: map[string]interface{}{"error": "strconv.Atoi: parsing \"foo\": invalid syntax"},
 		}},
 	},
 }
@@ -63,7 +66,7 @@ func TestQueryHandler(t *testing.T) {
 			assert.NoError(t, err)
 			assert.Equal(t, test.ErrorsLen, len(body.InvalidParams))
 			assert.Equal(t, test.Errors, body.InvalidParams)
-			assert.Equal(t, problem.DefaultInput.Title, body.Title)
+			assert.Equal(t, "Your form parameters could not be decoded", body.Title)
 			assert.Equal(t, problem.DefaultInput.Status, body.Status)
 			assert.Equal(t, test.ExpectedStatus, body.Status)
 		}
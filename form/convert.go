@@ -0,0 +1,119 @@
+package form
+
+import (
+	"encoding"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ExtendedBoolValues is a boolean vocabulary which can be set as
+// Decoder.BoolValues to accept yes/no, on/off and their short forms.
+var ExtendedBoolValues = map[string]bool{
+	"true":  true,
+	"false": false,
+	"yes":   true,
+	"no":    false,
+	"y":     true,
+	"n":     false,
+	"on":    true,
+	"off":   false,
+	"1":     true,
+	"0":     false,
+}
+
+// convert converts val to the type of e and stores it. Registered converters
+// take precedence, then types unmarshalling themselves, others are converted
+// from their kind so that named types such as `type Status string` are supported.
+func (d *Decoder) convert(e reflect.Value, val string, f *field) error {
+	if c, ok := d.converter(e.Type()); ok {
+		return convertWith(e, val, c)
+	}
+	// Pointers are only allocated once their value is converted.
+	if e.Kind() == reflect.Ptr {
+		v := reflect.New(e.Type().Elem())
+		if err := d.convert(v.Elem(), val, f); err != nil {
+			return err
+		}
+		e.Set(v)
+		return nil
+	}
+	if ok, err := d.convertTime(e, val, f); ok {
+		return err
+	}
+	if ok, err := convertSort(e, val, f); ok {
+		return err
+	}
+	if ok, err := convertFilter(e, val, f); ok {
+		return err
+	}
+	targetType := reflect.PtrTo(e.Type())
+	if targetType.Implements(TextUnmarshalerType) {
+		if err := e.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val)); err != nil {
+			return message("form.unmarshal_text", "error", err.Error())
+		}
+		return nil
+	}
+	if targetType.Implements(StringSetterType) {
+		if err := e.Addr().Interface().(StringSetter).Set(val); err != nil {
+			return message("form.unmarshal_text", "error", err.Error())
+		}
+		return nil
+	}
+
+	switch e.Kind() {
+	case reflect.String:
+		e.SetString(val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(val, 10, e.Type().Bits())
+		if isRangeErr(err) {
+			bits := uint(e.Type().Bits())
+			return message("form.int_range", "min", int64(-1)<<(bits-1), "max", int64(1)<<(bits-1)-1)
+		} else if err != nil {
+			return errIntSyntax
+		}
+		e.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v, err := strconv.ParseUint(val, 10, e.Type().Bits())
+		if isRangeErr(err) {
+			return message("form.int_range", "min", uint64(0), "max", ^uint64(0)>>uint(64-e.Type().Bits()))
+		} else if err != nil {
+			return errUintSyntax
+		}
+		e.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(val, e.Type().Bits())
+		if isRangeErr(err) {
+			return message("form.float_range", "bits", e.Type().Bits())
+		} else if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			// ParseFloat accepts NaN and Inf which are not numbers a client means to send
+			return errFloatSyntax
+		}
+		e.SetFloat(v)
+	case reflect.Bool:
+		v, err := d.parseBool(val)
+		if err != nil {
+			return errBoolSyntax
+		}
+		e.SetBool(v)
+	}
+	return nil
+}
+
+// parseBool parses val using the decoder boolean vocabulary.
+func (d *Decoder) parseBool(val string) (bool, error) {
+	if d.BoolValues == nil {
+		return strconv.ParseBool(val)
+	}
+	v, ok := d.BoolValues[strings.ToLower(val)]
+	if !ok {
+		return false, strconv.ErrSyntax
+	}
+	return v, nil
+}
+
+func isRangeErr(err error) bool {
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrRange
+}
@@ -0,0 +1,127 @@
+package form
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Converter converts a parameter value to a value of the type it is
+// registered for. Its errors are reported as parameter errors.
+type Converter func(string) (interface{}, error)
+
+// converters is the default registry, shared by all decoders.
+var converters sync.Map
+
+// RegisterConverter registers c as the default converter of t, used by all
+// decoders. It should be called at init, before any struct using t is
+// decoded or compiled. Registered converters take precedence over the
+// builtin conversions and apply to slice elements and pointers to t.
+// Structs with a converter are decoded from a single value instead of
+// nested keys.
+func RegisterConverter(t reflect.Type, c Converter) {
+	converters.Store(t, c)
+	// Plans compiled before the registration may not know t is a scalar
+	plans.Range(func(key, _ interface{}) bool {
+		plans.Delete(key)
+		return true
+	})
+}
+
+// RegisterConverter registers c as the converter of t for this decoder,
+// overriding the default one, see the RegisterConverter function. The
+// decoding plans depend on the types of the converters of a decoder, they
+// are cached for each set of types.
+func (d *Decoder) RegisterConverter(t reflect.Type, c Converter) {
+	if d.converters == nil {
+		d.converters = make(map[reflect.Type]Converter)
+	}
+	d.converters[t] = c
+	d.convertersKey = convertersKey(d.converters)
+}
+
+var (
+	typeIDsMu sync.Mutex
+	// typeIDs numbers the types registered on decoders, see convertersKey.
+	typeIDs = map[reflect.Type]int{}
+)
+
+// convertersKey returns a key identifying the types of converters, so that
+// decoders registering the same types share their plans.
+func convertersKey(converters map[reflect.Type]Converter) string {
+	ids := make([]int, 0, len(converters))
+	typeIDsMu.Lock()
+	for t := range converters {
+		id, ok := typeIDs[t]
+		if !ok {
+			id = len(typeIDs)
+			typeIDs[t] = id
+		}
+		ids = append(ids, id)
+	}
+	typeIDsMu.Unlock()
+	sort.Ints(ids)
+	var b strings.Builder
+	for i, id := range ids {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(id))
+	}
+	return b.String()
+}
+
+// WithConverter registers c as the converter of t for the decoder.
+func WithConverter(t reflect.Type, c Converter) Option {
+	return func(d *Decoder) {
+		d.RegisterConverter(t, c)
+	}
+}
+
+// converter returns the converter of t, the one of the decoder first.
+func (d *Decoder) converter(t reflect.Type) (Converter, bool) {
+	if c, ok := d.converters[t]; ok {
+		return c, true
+	}
+	return defaultConverter(t)
+}
+
+func defaultConverter(t reflect.Type) (Converter, bool) {
+	c, ok := converters.Load(t)
+	if !ok {
+		return nil, false
+	}
+	return c.(Converter), true
+}
+
+// hasConverter reports whether t, or the type it points to, has a default
+// converter.
+func hasConverter(t reflect.Type) bool {
+	if _, ok := defaultConverter(t); ok {
+		return true
+	}
+	_, ok := defaultConverter(indirectType(t))
+	return ok
+}
+
+// convertWith converts val with c and stores it onto e.
+func convertWith(e reflect.Value, val string, c Converter) error {
+	v, err := c(val)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	switch {
+	case !rv.IsValid():
+		return &ConverterError{Type: e.Type(), Value: v}
+	case rv.Type().AssignableTo(e.Type()):
+		e.Set(rv)
+	case rv.Type().ConvertibleTo(e.Type()):
+		e.Set(rv.Convert(e.Type()))
+	default:
+		return &ConverterError{Type: e.Type(), Value: v}
+	}
+	return nil
+}
@@ -3,6 +3,24 @@ Package form provides decoding functions for an http request forms (query, url-e
 The decoding consists of both unmarshalling of the query and validation.
 Validation consist of builtin tag options like required but also by
 self validation of your destination interface.
-Customs types can be used for unmarshalling if they implements encoding.TextUnmarshaller, encoding.BinaryUnmarshaler, StringSetter.
+Parameters are read from the source named by the field tag: form (the query merged with
+the body), query, postform, header or cookie, and file for multipart files.
+Struct fields tagged with form are decoded from nested keys, both dotted
+(address.city) and bracket (address[city]) notations are supported.
+Keys can have aliases (user_id|userId) and be derived from field names with a NamingStrategy.
+Slice fields are decoded from repeated keys, bracket keys (ids[]) or delimited values.
+The fields of embedded structs are promoted, their keys being optionally prefixed.
+Map fields with string keys are decoded from the keys nested into theirs (meta[color]).
+Types implementing ValuesUnmarshaler, such as IntRange, are decoded from their key and the keys
+nested into it (price=10..100 or price[gte]=10&price[lt]=100).
+Sort decodes sort=-created_at,name against the fields of its sortable option.
+Filter parses boolean expressions (age>=30 and status in (a,b)) into an AST walked by a FilterVisitor.
+Fields decodes sparse fieldsets (fields=id,owner.email) checked against registered response structs.
+Pagination decodes page, limit and signed cursors, and gives the Link header of the next pages.
+Customs types can be used for unmarshalling if they implements encoding.TextUnmarshaller or StringSetter.
+Other types, such as third-party ones, can be given a Converter with RegisterConverter.
+Files are decoded onto *multipart.FileHeader, io.ReadCloser, UploadedFile, their slices, or types
+implementing encoding.BinaryUnmarshaler.
+Encoder encodes structs back to url.Values or outgoing requests with the same tags.
 */
 package form
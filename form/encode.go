@@ -0,0 +1,475 @@
+package form
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types implementing TextMarshaler and BinaryMarshaler used on marshalling
+var (
+	TextMarshalerType   = reflect.TypeOf(new(encoding.TextMarshaler)).Elem()
+	BinaryMarshalerType = reflect.TypeOf(new(encoding.BinaryMarshaler)).Elem()
+	stringerType        = reflect.TypeOf(new(fmt.Stringer)).Elem()
+)
+
+// Encoder encodes the tagged fields of a struct, the reverse of Decoder. The
+// same tags and options are honored so that encoded values decode back to
+// the same struct: nested structs give dotted keys, slices repeated keys and
+// times are formatted with their layout option. As decoded values are split
+// with the sep option, a slice element containing it cannot be encoded.
+type Encoder struct {
+	// Location is the location of encoded times without tz option, UTC if nil.
+	Location *time.Location
+	// TagName and Naming are those of Decoder, aliases not being encoded.
+	TagName string
+	Naming  NamingStrategy
+}
+
+// NewEncoder returns a pointer to a new encoder.
+func NewEncoder() *Encoder {
+	return &Encoder{}
+}
+
+// Encode returns the values of the form, query and postform fields of v.
+// Nil pointers and slices are skipped, as well as the zero values of
+// fields with the omitempty option. As url.Values.Encode sorts keys, the
+// encoded values of equal structs are identical and can be used as cache keys.
+func (enc *Encoder) Encode(v interface{}) (url.Values, error) {
+	e, err := enc.encode(v)
+	if err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	for _, source := range []string{SourceForm, SourceQuery, SourcePostForm} {
+		for key, vals := range e.values[source] {
+			values[key] = append(values[key], vals...)
+		}
+	}
+	return values, nil
+}
+
+// EncodeRequest encodes the fields of v onto r: form and query fields are
+// added to the URL query, path fields replace the {name} wildcards of the
+// URL path, header and cookie fields are added to the headers. Postform
+// fields give an url-encoded body, a multipart one if v has files, and
+// EncodeRequest fails if r already has a body.
+func (enc *Encoder) EncodeRequest(r *http.Request, v interface{}) error {
+	e, err := enc.encode(v)
+	if err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	for _, source := range []string{SourceForm, SourceQuery} {
+		for key, vals := range e.values[source] {
+			query[key] = append(query[key], vals...)
+		}
+	}
+	r.URL.RawQuery = query.Encode()
+	if err := setPathParams(r.URL, e.values[SourcePath]); err != nil {
+		return err
+	}
+	for key, vals := range e.values[SourceHeader] {
+		for _, val := range vals {
+			r.Header.Add(key, val)
+		}
+	}
+	for _, name := range sortedKeys(e.values[SourceCookie]) {
+		for _, val := range e.values[SourceCookie][name] {
+			r.AddCookie(&http.Cookie{Name: name, Value: val})
+		}
+	}
+	if len(e.values[SourcePostForm]) == 0 && len(e.files) == 0 {
+		return nil
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		return errors.New("form: cannot encode postform and file fields, the request already has a body")
+	}
+	body := new(bytes.Buffer)
+	contentType := "application/x-www-form-urlencoded"
+	if len(e.files) == 0 {
+		body.WriteString(e.values[SourcePostForm].Encode())
+	} else if contentType, err = e.writeMultipart(body); err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", contentType)
+	r.ContentLength = int64(body.Len())
+	b := body.Bytes()
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+	return nil
+}
+
+// encoded holds the values of an encoded struct by source.
+type encoded struct {
+	values map[string]url.Values
+	files  map[string][]filePart
+}
+
+// filePart is an encoded file, opened when written.
+type filePart struct {
+	filename string
+	open     func() (io.ReadCloser, error)
+}
+
+func (enc *Encoder) encode(v interface{}) (*encoded, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("form: cannot encode nil")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("form: cannot encode %s, a struct is expected", rv.Type())
+	}
+	p, err := planOf(rv.Type(), newPlanConfig(enc.TagName, enc.Naming))
+	if err != nil {
+		return nil, err
+	}
+	e := &encoded{values: make(map[string]url.Values), files: make(map[string][]filePart)}
+	if err := enc.encodeStruct(e, rv, p, ""); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// encodeStruct encodes the fields of elem following its plan, prefix being
+// the key of elem when it is nested into another struct.
+func (enc *Encoder) encodeStruct(e *encoded, elem reflect.Value, p *plan, prefix string) error {
+	for _, f := range p.fields {
+		v, ok := embeddedField(elem, f.index)
+		if !ok {
+			continue
+		}
+		key := f.name
+		if isKeyed(f.source) {
+			key = joinKey(prefix, f.name)
+		}
+		if f.opts.Has("omitempty") && v.IsZero() {
+			continue
+		}
+		var err error
+		switch f.kind {
+		case nestedField:
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					continue
+				}
+				v = v.Elem()
+			}
+			err = enc.encodeStruct(e, v, f.nested, key)
+		case sliceField:
+			for i := 0; i < v.Len(); i++ {
+				if err = enc.add(e, key, v.Index(i), f); err != nil {
+					break
+				}
+			}
+		case mapField:
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			for _, k := range keys {
+				if err = enc.add(e, joinKey(key, k.String()), v.MapIndex(k), f); err != nil {
+					break
+				}
+			}
+		case valuesField, paginationField:
+			err = enc.addValues(e, key, v, f)
+		case fileField:
+			err = e.addFiles(key, v)
+		default:
+			err = enc.add(e, key, v, f)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// embeddedField returns the field of elem at index, it reports false if the
+// field is within a nil embedded pointer.
+func embeddedField(elem reflect.Value, index []int) (reflect.Value, bool) {
+	v := elem.Field(index[0])
+	for _, x := range index[1:] {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// addValues adds the values of the ValuesMarshaler v, nil pointers being
+// skipped, a value of v without key being the one of key.
+func (enc *Encoder) addValues(e *encoded, key string, v reflect.Value, f *field) error {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	m, ok := marshaler(reflect.Indirect(v), ValuesMarshalerType)
+	if !ok {
+		return fmt.Errorf("form: cannot encode %s: %s does not implement form.ValuesMarshaler", key, v.Type())
+	}
+	values, err := m.(ValuesMarshaler).MarshalValues()
+	if err != nil {
+		return fmt.Errorf("form: cannot encode %s: an error occured via MarshalValues: %v", key, err)
+	}
+	if e.values[f.source] == nil {
+		e.values[f.source] = url.Values{}
+	}
+	for suffix, vals := range values {
+		k := key
+		if suffix != "" {
+			k = joinKey(key, suffix)
+		}
+		e.values[f.source][k] = append(e.values[f.source][k], vals...)
+	}
+	return nil
+}
+
+// add formats v and adds it to the values of key, nil pointers being skipped.
+func (enc *Encoder) add(e *encoded, key string, v reflect.Value, f *field) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	val, err := enc.format(v, f)
+	if err != nil {
+		return fmt.Errorf("form: cannot encode %s: %v", key, err)
+	}
+	if f.kind == sliceField && strings.Contains(val, f.sep) {
+		return fmt.Errorf("form: cannot encode %s: %q contains the separator %q and would be split when decoded", key, val, f.sep)
+	}
+	if e.values[f.source] == nil {
+		e.values[f.source] = url.Values{}
+	}
+	e.values[f.source].Add(key, val)
+	return nil
+}
+
+// format returns the string of v decoding back to v, see Decoder.convert.
+func (enc *Encoder) format(v reflect.Value, f *field) (string, error) {
+	switch v.Type() {
+	case timeType:
+		return enc.formatTime(v.Interface().(time.Time), f), nil
+	case durationType:
+		return time.Duration(v.Int()).String(), nil
+	}
+	if m, ok := marshaler(v, TextMarshalerType); ok {
+		b, err := m.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", fmt.Errorf("an error occured via MarshalText: %v", err)
+		}
+		return string(b), nil
+	}
+	if isUnmarshaler(v.Type()) {
+		if s, ok := marshaler(v, stringerType); ok {
+			return s.(fmt.Stringer).String(), nil
+		}
+		return "", fmt.Errorf("%s does not implement encoding.TextMarshaler", v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// formatTime formats t with the layout option of f, see Decoder.parseTime.
+func (enc *Encoder) formatTime(t time.Time, f *field) string {
+	switch f.layout {
+	case LayoutUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case LayoutUnixMilli:
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	}
+	loc := f.loc
+	if loc == nil {
+		loc = enc.Location
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(f.layout)
+}
+
+// marshaler returns v, or its address when addressable, as an interface
+// value if it implements the interface type i.
+func marshaler(v reflect.Value, i reflect.Type) (interface{}, bool) {
+	if v.Type().Implements(i) {
+		return v.Interface(), true
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(i) {
+		return v.Addr().Interface(), true
+	}
+	return nil, false
+}
+
+// addFiles adds the files of the file field v.
+func (e *encoded) addFiles(key string, v reflect.Value) error {
+	switch v.Type() {
+	case fileHeaderType:
+		if !v.IsNil() {
+			e.addFileHeader(key, v.Interface().(*multipart.FileHeader))
+		}
+	case fileHeadersType:
+		for _, header := range v.Interface().([]*multipart.FileHeader) {
+			e.addFileHeader(key, header)
+		}
+	case readCloserType:
+		if !v.IsNil() {
+			rc := v.Interface().(io.ReadCloser)
+			e.addFile(key, key, func() (io.ReadCloser, error) { return rc, nil })
+		}
+	case uploadedFileType:
+		e.addUploadedFile(key, v.Interface().(UploadedFile))
+	case uploadedFilePtrType:
+		if !v.IsNil() {
+			e.addUploadedFile(key, *v.Interface().(*UploadedFile))
+		}
+	case uploadedFilesType:
+		for _, file := range v.Interface().([]UploadedFile) {
+			e.addUploadedFile(key, file)
+		}
+	default:
+		m, ok := marshaler(v, BinaryMarshalerType)
+		if !ok {
+			return fmt.Errorf("form: cannot encode %s: %s does not implement encoding.BinaryMarshaler", key, v.Type())
+		}
+		b, err := m.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("form: cannot encode %s: an error occured via MarshalBinary: %v", key, err)
+		}
+		e.addFile(key, key, func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		})
+	}
+	return nil
+}
+
+func (e *encoded) addFileHeader(key string, header *multipart.FileHeader) {
+	e.addFile(key, header.Filename, func() (io.ReadCloser, error) { return header.Open() })
+}
+
+// addUploadedFile adds file, skipped if it has no temporary file.
+func (e *encoded) addUploadedFile(key string, file UploadedFile) {
+	if file.Path == "" {
+		return
+	}
+	e.addFile(key, file.Filename, func() (io.ReadCloser, error) { return os.Open(file.Path) })
+}
+
+func (e *encoded) addFile(key, filename string, open func() (io.ReadCloser, error)) {
+	e.files[key] = append(e.files[key], filePart{filename: filename, open: open})
+}
+
+// writeMultipart writes the postform values and the files as a multipart
+// body, sorted by key, and returns its content type.
+func (e *encoded) writeMultipart(w io.Writer) (string, error) {
+	writer := multipart.NewWriter(w)
+	values := e.values[SourcePostForm]
+	for _, key := range sortedKeys(values) {
+		for _, val := range values[key] {
+			if err := writer.WriteField(key, val); err != nil {
+				return "", err
+			}
+		}
+	}
+	keys := make([]string, 0, len(e.files))
+	for key := range e.files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, file := range e.files[key] {
+			if err := writeFile(writer, key, file); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return writer.FormDataContentType(), nil
+}
+
+func writeFile(writer *multipart.Writer, key string, file filePart) error {
+	w, err := writer.CreateFormFile(key, file.filename)
+	if err != nil {
+		return err
+	}
+	rc, err := file.open()
+	if err != nil {
+		return fmt.Errorf("form: cannot encode %s: %v", key, err)
+	}
+	defer rc.Close()
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+// setPathParams replaces the {name} and {name...} wildcards of the path of u
+// with the escaped values of params.
+func setPathParams(u *url.URL, params url.Values) error {
+	if len(params) == 0 {
+		return nil
+	}
+	path := u.Path
+	for _, name := range sortedKeys(params) {
+		val := params.Get(name)
+		switch {
+		case strings.Contains(path, "{"+name+"}"):
+			path = strings.Replace(path, "{"+name+"}", url.PathEscape(val), -1)
+		case strings.Contains(path, "{"+name+"...}"):
+			segments := strings.Split(val, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			path = strings.Replace(path, "{"+name+"...}", strings.Join(segments, "/"), -1)
+		default:
+			return fmt.Errorf("form: cannot encode %s, the path %s has no {%s} wildcard", name, u.Path, name)
+		}
+	}
+	unescaped, err := url.PathUnescape(path)
+	if err != nil {
+		return err
+	}
+	u.Path, u.RawPath = unescaped, path
+	return nil
+}
+
+func sortedKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
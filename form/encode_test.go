@@ -0,0 +1,165 @@
+package form_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sganon/go-request/form"
+	"github.com/stretchr/testify/assert"
+)
+
+type encodeInput struct {
+	Name    string          `query:"name"`
+	Limit   int             `query:"limit,omitempty"`
+	Ratio   float64         `query:"ratio,omitempty"`
+	Active  *bool           `query:"active"`
+	IDs     []int           `query:"ids"`
+	List    form.IntList    `query:"list,omitempty"`
+	Tags    form.StringList `query:"tags,omitempty"`
+	Since   time.Time       `query:"since,layout=2006-01-02,omitempty"`
+	Day     form.Date       `query:"day,omitempty"`
+	Timeout time.Duration   `query:"timeout,omitempty"`
+	Filter  *struct {
+		Status string `query:"status"`
+	} `query:"filter"`
+	ID      string `path:"id"`
+	Token   string `header:"x-token,omitempty"`
+	Session int    `cookie:"session,omitempty"`
+	Comment string `postform:"comment,omitempty"`
+}
+
+func TestEncoder(t *testing.T) {
+	active := true
+	input := encodeInput{
+		Name:    "foo bar",
+		Ratio:   0.5,
+		Active:  &active,
+		IDs:     []int{3, 1, 2},
+		List:    form.IntList{4, 5},
+		Tags:    form.StringList{"a", "b"},
+		Since:   time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		Day:     form.Date{Year: 2020, Month: 2, Day: 3},
+		Timeout: 90 * time.Second,
+		Filter: &struct {
+			Status string `query:"status"`
+		}{Status: "open"},
+		ID:      "a/b",
+		Token:   "secret",
+		Session: 42,
+		Comment: "hello",
+	}
+	values, err := form.NewEncoder().Encode(input)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"active=true&comment=hello&day=2020-02-03&filter.status=open&ids=3&ids=1&ids=2&list=4%2C5&name=foo+bar&ratio=0.5&since=2020-01-02&tags=a%2Cb&timeout=1m30s",
+		values.Encode(),
+	)
+
+	values, err = form.NewEncoder().Encode(&encodeInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, url.Values{"name": {""}}, values, "nil pointers, empty slices and omitempty fields should be skipped")
+
+	req, err := http.NewRequest("POST", "http://localhost/items/{id}?page=2", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, form.NewEncoder().EncodeRequest(req, input))
+	assert.Equal(t, "/items/a%2Fb", req.URL.EscapedPath())
+	assert.Equal(t, "2", req.URL.Query().Get("page"))
+	assert.Equal(t, "secret", req.Header.Get("X-Token"))
+	assert.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+	b, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, "comment=hello", string(b))
+
+	req.Body = ioutil.NopCloser(strings.NewReader(string(b)))
+	var output encodeInput
+	decoder := form.NewDecoder(req, form.WithPathParams(form.PathVarsFunc(func(r *http.Request) map[string]string {
+		return map[string]string{"id": "a/b"}
+	})))
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, input, output, "encoded values should decode back to the same struct")
+
+	req, _ = http.NewRequest("GET", "http://localhost/items", nil)
+	err = form.NewEncoder().EncodeRequest(req, encodeInput{ID: "1"})
+	assert.Error(t, err, "a path without wildcard cannot be encoded")
+
+	values, err = form.NewEncoder().Encode(struct {
+		Meta map[string]int `form:"meta"`
+	}{Meta: map[string]int{"size": 9, "count": 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, "meta.count=1&meta.size=9", values.Encode())
+
+	_, err = form.NewEncoder().Encode(struct {
+		Value failingMarshaler `query:"value"`
+	}{})
+	assert.EqualError(t, err, "form: cannot encode value: an error occured via MarshalText: failed")
+}
+
+func TestEncoderSeparator(t *testing.T) {
+	type input struct {
+		Tags  []string `form:"tags"`
+		Names []string `form:"names,sep=|"`
+	}
+	_, err := form.NewEncoder().Encode(input{Tags: []string{"a,b"}})
+	assert.EqualError(t, err, `form: cannot encode tags: "a,b" contains the separator "," and would be split when decoded`)
+
+	want := input{Tags: []string{"a", "b"}, Names: []string{"a,b", "c"}}
+	values, err := form.NewEncoder().Encode(want)
+	assert.NoError(t, err)
+	req, err := http.NewRequest("GET", "http://localhost/?"+values.Encode(), nil)
+	assert.NoError(t, err)
+	var output input
+	decoder := form.NewDecoder(req)
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, want, output, "elements should decode back to the same slice")
+}
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalText() ([]byte, error) {
+	return nil, errors.New("failed")
+}
+
+func (*failingMarshaler) UnmarshalText([]byte) error {
+	return nil
+}
+
+func TestEncoderFiles(t *testing.T) {
+	req := newMultipartRequest(t, map[string]string{"name": "foo"},
+		part{"avatar", "avatar.png", "avatar content"},
+		part{"docs", "a.txt", "a"},
+		part{"docs", "b.txt", "b"},
+		part{"content", "content.bin", "binary"},
+	)
+	var input fileInput
+	decoder := form.NewDecoder(req)
+	decoder.Decode(&input)
+	defer decoder.Cleanup()
+	assert.Nil(t, decoder.Input)
+
+	req, _ = http.NewRequest("POST", "http://localhost/", nil)
+	assert.NoError(t, form.NewEncoder().EncodeRequest(req, input))
+
+	var output fileInput
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&output)
+	defer decoder.Cleanup()
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, "foo", output.Name)
+	assert.Equal(t, input.Avatar.SHA256, output.Avatar.SHA256)
+	assert.Equal(t, "avatar.png", output.Avatar.Filename)
+	if assert.Len(t, output.Docs, 2) {
+		assert.Equal(t, "b.txt", output.Docs[1].Filename)
+	}
+	assert.Equal(t, input.Content, output.Content)
+	assert.Equal(t, "name=foo", req.URL.RawQuery)
+
+	req, _ = http.NewRequest("POST", "http://localhost/", strings.NewReader("{}"))
+	err := form.NewEncoder().EncodeRequest(req, input)
+	assert.Error(t, err, "files cannot be encoded onto a request with a body")
+}
@@ -0,0 +1,31 @@
+package form
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TagError reports an invalid form tag. Contrary to parameter errors it is
+// a programming error, not a client one.
+type TagError struct {
+	Field  string
+	Option string
+	Err    error
+}
+
+// Error implements error interface
+func (e *TagError) Error() string {
+	return fmt.Sprintf("form: invalid option %s of field %s: %v", e.Option, e.Field, e.Err)
+}
+
+// ConverterError reports a registered converter returning a value which
+// cannot be stored onto its type. As TagError it is a programming error.
+type ConverterError struct {
+	Type  reflect.Type
+	Value interface{}
+}
+
+// Error implements error interface
+func (e *ConverterError) Error() string {
+	return fmt.Sprintf("form: the converter of %s returned a value of type %T", e.Type, e.Value)
+}
@@ -0,0 +1,475 @@
+package form
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var fieldsType = reflect.TypeOf(Fields{})
+
+// resources maps the names of the resources to their response type, see
+// RegisterResource.
+var resources sync.Map
+
+// jsonFieldsCache caches the JSON fields of each struct type.
+var jsonFieldsCache sync.Map
+
+// RegisterResource registers the response type of the resource name, a
+// struct, a pointer to a struct or their reflect.Type, so that the paths of
+// Fields are checked against its JSON tags. It should be called at init.
+func RegisterResource(name string, v interface{}) {
+	t, ok := v.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(v)
+	}
+	resources.Store(name, indirectType(t))
+}
+
+func resourceType(name string) (reflect.Type, bool) {
+	t, ok := resources.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return t.(reflect.Type), true
+}
+
+// Fields is a sparse fieldset decoded from ?fields=id,name,owner.email, the
+// paths of its resource, and fields[articles]=title,body, the paths of other
+// resources. The resource option names the registered resource of the field,
+// e.g `form:"fields,resource=users"`, paths being checked against the JSON
+// tags of their resource.
+type Fields struct {
+	// Set is the selection of the resource of the field, nil if none is
+	// given.
+	Set FieldSet
+	// Resources are the selections of the other resources by name.
+	Resources map[string]FieldSet
+}
+
+// FieldSet is a tree of selected JSON fields, a nil FieldSet selecting all
+// the fields: owner.email gives {"owner": {"email": nil}}.
+type FieldSet map[string]FieldSet
+
+// ParseFieldSet parses comma separated paths, see FieldSet.
+func ParseFieldSet(s string) (FieldSet, error) {
+	set := FieldSet{}
+	for _, path := range strings.Split(s, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if err := set.add(strings.Split(path, ".")); err != nil {
+			return nil, err
+		}
+	}
+	if len(set) == 0 {
+		return nil, message("form.fields_syntax")
+	}
+	return set, nil
+}
+
+// add selects path, selecting a field as a whole winning over the selection
+// of some of its fields.
+func (s FieldSet) add(path []string) error {
+	name := path[0]
+	if name == "" {
+		return message("form.fields_empty")
+	}
+	sub, ok := s[name]
+	if ok && sub == nil {
+		return nil
+	}
+	if len(path) == 1 {
+		s[name] = nil
+		return nil
+	}
+	if sub == nil {
+		sub = FieldSet{}
+		s[name] = sub
+	}
+	return sub.add(path[1:])
+}
+
+// Has reports whether the dotted path is selected, all paths being selected
+// by a nil FieldSet.
+func (s FieldSet) Has(path string) bool {
+	for _, name := range strings.Split(path, ".") {
+		if s == nil {
+			return true
+		}
+		sub, ok := s[name]
+		if !ok {
+			return false
+		}
+		s = sub
+	}
+	return true
+}
+
+// Paths returns the sorted dotted paths of the selected fields.
+func (s FieldSet) Paths() []string {
+	var paths []string
+	for name, sub := range s {
+		if sub == nil {
+			paths = append(paths, name)
+			continue
+		}
+		for _, path := range sub.Paths() {
+			paths = append(paths, name+"."+path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (s FieldSet) String() string {
+	return strings.Join(s.Paths(), ",")
+}
+
+// UnmarshalValues implements ValuesUnmarshaler
+func (f *Fields) UnmarshalValues(values url.Values) error {
+	var fields Fields
+	for name, vals := range values {
+		set, err := ParseFieldSet(strings.Join(vals, ","))
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			fields.Set = set
+			continue
+		}
+		if fields.Resources == nil {
+			fields.Resources = make(map[string]FieldSet)
+		}
+		fields.Resources[name] = set
+	}
+	*f = fields
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (f *Fields) UnmarshalText(text []byte) error {
+	return f.UnmarshalValues(url.Values{"": {string(text)}})
+}
+
+// MarshalValues implements ValuesMarshaler
+func (f Fields) MarshalValues() (url.Values, error) {
+	values := url.Values{}
+	if f.Set != nil {
+		values.Set("", f.Set.String())
+	}
+	for name, set := range f.Resources {
+		values.Set(name, set.String())
+	}
+	return values, nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (f Fields) MarshalText() ([]byte, error) {
+	if len(f.Resources) > 0 {
+		return nil, errors.New("the fields of other resources cannot be marshalled as text")
+	}
+	return []byte(f.Set.String()), nil
+}
+
+// checkField implements fieldChecker, checking f against the resource option
+// of its field.
+func (f *Fields) checkField(field *field) error {
+	return f.check(field.resource)
+}
+
+// check checks the paths of f against the JSON fields of their resources,
+// resource being the one of the field, none if empty.
+func (f *Fields) check(resource string) error {
+	if t, ok := resourceType(resource); ok && f.Set != nil {
+		if err := checkFieldSet(f.Set, t, ""); err != nil {
+			return err
+		}
+	}
+	names := make([]string, 0, len(f.Resources))
+	for name := range f.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t, ok := resourceType(name)
+		if !ok {
+			return message("form.fields_resource", "resource", name)
+		}
+		if err := checkFieldSet(f.Resources[name], t, ""); err != nil {
+			return message("form.fields_of", "resource", name, "reason", err)
+		}
+	}
+	return nil
+}
+
+// checkFieldSet checks the paths of set, nested into path, are fields of t.
+func checkFieldSet(set FieldSet, t reflect.Type, path string) error {
+	t = jsonElem(t)
+	if t.Kind() == reflect.Map {
+		// Any key can be selected
+		return nil
+	}
+	if t.Kind() != reflect.Struct || isJSONLeaf(t) {
+		return message("form.fields_leaf", "field", path)
+	}
+	fields := jsonFields(t)
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		jf, ok := fields[name]
+		if !ok {
+			expected := make([]string, 0, len(fields))
+			for name := range fields {
+				expected = append(expected, name)
+			}
+			sort.Strings(expected)
+			return message("form.fields_unknown", "field", joinKey(path, name), "expected", expected)
+		}
+		if set[name] == nil {
+			continue
+		}
+		if err := checkFieldSet(set[name], t.FieldByIndex(jf.index).Type, joinKey(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prune returns v reduced to the selected fields, to be encoded as JSON.
+// Structs become maps keyed by their JSON names, the selection of their
+// registered resource applying to the structs which are not selected
+// through a path. Values implementing json.Marshaler or
+// encoding.TextMarshaler are kept as is.
+func (f Fields) Prune(v interface{}) interface{} {
+	return f.prune(reflect.ValueOf(v), f.Set)
+}
+
+func (f Fields) prune(v reflect.Value, set FieldSet) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if isJSONLeaf(v.Type()) {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if set == nil {
+			set = f.resourceSet(v.Type())
+		}
+		m := make(map[string]interface{})
+		for name, jf := range jsonFields(v.Type()) {
+			sub, ok := set[name]
+			if set != nil && !ok {
+				continue
+			}
+			fv, ok := embeddedField(v, jf.index)
+			if !ok || (jf.omitEmpty && isEmptyJSON(fv)) {
+				continue
+			}
+			if jf.quoted && !(fv.Kind() == reflect.Ptr && fv.IsNil()) {
+				b, err := json.Marshal(fv.Interface())
+				if err == nil {
+					m[name] = string(b)
+					continue
+				}
+			}
+			m[name] = f.prune(fv, sub)
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = f.prune(v.Index(i), set)
+		}
+		return items
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.IsNil() {
+			return v.Interface()
+		}
+		m := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			sub, ok := set[k.String()]
+			if set != nil && !ok {
+				continue
+			}
+			m[k.String()] = f.prune(v.MapIndex(k), sub)
+		}
+		return m
+	}
+	return v.Interface()
+}
+
+// resourceSet returns the selection of the registered resource of type t,
+// nil if none.
+func (f Fields) resourceSet(t reflect.Type) FieldSet {
+	var set FieldSet
+	resources.Range(func(name, rt interface{}) bool {
+		if rt.(reflect.Type) == t {
+			set = f.Resources[name.(string)]
+			return set == nil
+		}
+		return true
+	})
+	return set
+}
+
+// jsonField is a field of a struct encoded by encoding/json.
+type jsonField struct {
+	index     []int
+	omitEmpty bool
+	quoted    bool
+}
+
+// jsonFields returns the fields of the struct type t by JSON name, the ones
+// of embedded structs being promoted as encoding/json does.
+func jsonFields(t reflect.Type) map[string]jsonField {
+	if fields, ok := jsonFieldsCache.Load(t); ok {
+		return fields.(map[string]jsonField)
+	}
+	type candidate struct {
+		jsonField
+		tagged bool
+		count  int
+	}
+	candidates := map[string]*candidate{}
+	embedding := map[reflect.Type]bool{t: true}
+	var collect func(t reflect.Type, index []int)
+	collect = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			path := append(append([]int(nil), index...), i)
+			opts := strings.Split(tag, ",")
+			name := opts[0]
+			ft := indirectType(sf.Type)
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				// Unlike encoding/json the fields of unexported embedded
+				// structs are skipped, as their values cannot be read
+				if sf.PkgPath == "" && !embedding[ft] {
+					embedding[ft] = true
+					collect(ft, path)
+					delete(embedding, ft)
+				}
+				continue
+			}
+			if sf.PkgPath != "" {
+				continue
+			}
+			tagged := name != ""
+			if !tagged {
+				name = sf.Name
+			}
+			c, ok := candidates[name]
+			switch {
+			case !ok || len(path) < len(c.index) || (len(path) == len(c.index) && tagged && !c.tagged):
+				c = &candidate{jsonField: jsonField{index: path}, tagged: tagged, count: 1}
+				for _, opt := range opts[1:] {
+					c.omitEmpty = c.omitEmpty || opt == "omitempty"
+					c.quoted = c.quoted || (opt == "string" && isJSONScalar(ft))
+				}
+				candidates[name] = c
+			case len(path) == len(c.index) && tagged == c.tagged:
+				c.count++
+			}
+		}
+	}
+	collect(t, nil)
+	fields := make(map[string]jsonField, len(candidates))
+	for name, c := range candidates {
+		// Ambiguous fields are not encoded
+		if c.count == 1 {
+			fields[name] = c.jsonField
+		}
+	}
+	jsonFieldsCache.Store(t, fields)
+	return fields
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf(new(json.Marshaler)).Elem()
+	textMarshalerType = reflect.TypeOf(new(encoding.TextMarshaler)).Elem()
+)
+
+// isJSONLeaf reports whether values of type t are encoded as a whole.
+func isJSONLeaf(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	if t.Implements(jsonMarshalerType) || pt.Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || pt.Implements(textMarshalerType) {
+		return true
+	}
+	// Byte slices are encoded in base64
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// jsonElem returns the type whose fields are selected through t, the
+// element type of pointers, slices and arrays.
+func jsonElem(t reflect.Type) reflect.Type {
+	for !isJSONLeaf(t) && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isEmptyJSON reports whether v is omitted by the omitempty option.
+func isEmptyJSON(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return isJSONScalar(v.Type()) && v.IsZero()
+}
+
+// isJSONScalar reports whether t is a boolean, a number or a string, the
+// types accepting the string option.
+func isJSONScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// compileFieldsOptions checks the resource option, only accepted by Fields
+// fields, names a registered resource.
+func (f *field) compileFieldsOptions(t reflect.Type) error {
+	resource, ok := f.opts.Get("resource")
+	if !ok {
+		return nil
+	}
+	if indirectType(t) != fieldsType {
+		return &TagError{Option: "resource", Err: fmt.Errorf("only form.Fields accepts the resource option, got %s", t)}
+	}
+	if _, ok := resourceType(resource); !ok {
+		return &TagError{Option: "resource", Err: fmt.Errorf("unknown resource %q, see RegisterResource", resource)}
+	}
+	f.resource = resource
+	return nil
+}
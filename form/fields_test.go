@@ -0,0 +1,141 @@
+package form_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sganon/go-request/form"
+	"github.com/sganon/go-request/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+type Audit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type userResponse struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+type articleResponse struct {
+	Audit
+	ID       int             `json:"id"`
+	Title    string          `json:"title"`
+	Body     string          `json:"body"`
+	Views    int             `json:"views,string"`
+	Owner    *userResponse   `json:"owner"`
+	Readers  []userResponse  `json:"readers"`
+	Meta     map[string]bool `json:"meta"`
+	Internal string          `json:"-"`
+}
+
+func init() {
+	form.RegisterResource("articles", articleResponse{})
+	form.RegisterResource("users", &userResponse{})
+}
+
+type fieldsInput struct {
+	Fields form.Fields `query:"fields,resource=articles"`
+}
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   form.Fields
+		err    string
+		code   string
+		id     string
+		params map[string]interface{}
+	}{
+		{query: "fields=id,owner.email,owner,readers.name,meta.x", want: form.Fields{Set: form.FieldSet{"id": nil, "owner": nil, "readers": {"name": nil}, "meta": {"x": nil}}}},
+		{query: "fields=created_at&fields[users]=name", want: form.Fields{Set: form.FieldSet{"created_at": nil}, Resources: map[string]form.FieldSet{"users": {"name": nil}}}},
+		{query: "fields=owner.emial", err: `unknown field "owner.emial", expected one of: email, id, name`, code: "invalid_value", id: "form.fields_unknown", params: map[string]interface{}{"field": "owner.emial", "expected": []string{"email", "id", "name"}}},
+		{query: "fields=Internal", err: `unknown field "Internal", expected one of: body, created_at, id, meta, owner, readers, title, views`, code: "invalid_value", id: "form.fields_unknown", params: map[string]interface{}{"field": "Internal", "expected": []string{"body", "created_at", "id", "meta", "owner", "readers", "title", "views"}}},
+		{query: "fields=title.x", err: `field "title" has no fields`, code: "invalid_value", id: "form.fields_leaf", params: map[string]interface{}{"field": "title"}},
+		{query: "fields=created_at.x", err: `field "created_at" has no fields`, code: "invalid_value", id: "form.fields_leaf", params: map[string]interface{}{"field": "created_at"}},
+		{query: "fields=id,,owner..name", err: "syntax error: empty field in path", code: "invalid_format", id: "form.fields_empty"},
+		{query: "fields=,", err: "syntax error: expected fields such as id,name,owner.email", code: "invalid_format", id: "form.fields_syntax"},
+		{query: "fields[users]=age", err: `users: unknown field "age", expected one of: email, id, name`, code: "invalid_value", id: "form.fields_of", params: map[string]interface{}{
+			"resource": "users",
+			"reason":   &problem.Message{ID: "form.fields_unknown", Code: "invalid_value", Params: map[string]interface{}{"field": "age", "expected": []string{"email", "id", "name"}}},
+		}},
+		{query: "fields[comments]=id", err: `unknown resource "comments"`, code: "invalid_value", id: "form.fields_resource", params: map[string]interface{}{"resource": "comments"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var output fieldsInput
+			decoder := form.NewDecoder(httptest.NewRequest("GET", "/?"+tt.query, nil))
+			decoder.Decode(&output)
+			if tt.err != "" {
+				if assert.NotNil(t, decoder.Input) {
+					assert.Equal(t, []problem.ParamError{{Field: "fields", Reason: tt.err, In: "query", Code: tt.code, MessageID: tt.id, Params: tt.params}}, decoder.Input.InvalidParams)
+				}
+				return
+			}
+			assert.Nil(t, decoder.Input)
+			assert.Equal(t, tt.want, output.Fields)
+		})
+	}
+
+	set, err := form.ParseFieldSet("owner.email,id")
+	assert.NoError(t, err)
+	assert.True(t, set.Has("owner.email"))
+	assert.False(t, set.Has("owner.name"))
+	assert.True(t, form.FieldSet(nil).Has("owner.name"), "a nil set should select all fields")
+	assert.Equal(t, "id,owner.email", set.String())
+
+	values, err := form.NewEncoder().Encode(fieldsInput{Fields: form.Fields{Set: set, Resources: map[string]form.FieldSet{"users": {"name": nil}}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "fields=id%2Cowner.email&fields.users=name", values.Encode())
+
+	assert.Error(t, form.Compile(struct {
+		Fields form.Fields `query:"fields,resource=unknown"`
+	}{}), "the resource should be registered")
+}
+
+func TestFieldsPrune(t *testing.T) {
+	article := articleResponse{
+		Audit:   Audit{CreatedAt: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+		ID:      1,
+		Title:   "title",
+		Body:    "body",
+		Views:   42,
+		Owner:   &userResponse{ID: 2, Name: "bob", Email: "bob@example.com"},
+		Readers: []userResponse{{ID: 3, Name: "alice"}, {ID: 4, Name: "carol", Email: "carol@example.com"}},
+	}
+	tests := []struct {
+		fields form.Fields
+		want   string
+	}{
+		{
+			fields: form.Fields{Set: form.FieldSet{"id": nil, "owner": {"email": nil}, "views": nil}},
+			want:   `{"id":1,"owner":{"email":"bob@example.com"},"views":"42"}`,
+		},
+		{
+			fields: form.Fields{Set: form.FieldSet{"created_at": nil, "readers": nil}, Resources: map[string]form.FieldSet{"users": {"name": nil}}},
+			want:   `{"created_at":"2020-01-02T00:00:00Z","readers":[{"name":"alice"},{"name":"carol"}]}`,
+		},
+		{
+			fields: form.Fields{Resources: map[string]form.FieldSet{"articles": {"title": nil}}},
+			want:   `{"title":"title"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			b, err := json.Marshal(tt.fields.Prune(&article))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(b))
+		})
+	}
+
+	full, _ := json.Marshal(article)
+	pruned, _ := json.Marshal(form.Fields{}.Prune(article))
+	assert.JSONEq(t, string(full), string(pruned), "no selection should keep the JSON encoding")
+
+	b, _ := json.Marshal(form.Fields{Set: form.FieldSet{"id": nil}}.Prune([]articleResponse{article, {ID: 5}}))
+	assert.Equal(t, `[{"id":1},{"id":5}]`, string(b))
+}
@@ -0,0 +1,351 @@
+package form
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/alecthomas/units"
+)
+
+// Types supported by file tags, with encoding.BinaryUnmarshaler
+var (
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType     = reflect.TypeOf([]*multipart.FileHeader(nil))
+	readCloserType      = reflect.TypeOf((*io.ReadCloser)(nil)).Elem()
+	uploadedFileType    = reflect.TypeOf(UploadedFile{})
+	uploadedFilesType   = reflect.TypeOf([]UploadedFile(nil))
+	uploadedFilePtrType = reflect.TypeOf((*UploadedFile)(nil))
+)
+
+// UploadedFile is a file of a multipart body copied to a temporary file,
+// its size and SHA-256 hash being computed while it is copied. The temporary
+// file is removed by Decoder.Cleanup.
+type UploadedFile struct {
+	// Filename is the sanitized name given by the client, see SanitizeFilename.
+	Filename string
+	// ContentType is the content type given by the client.
+	ContentType string
+	Size        int64
+	// SHA256 is the hex encoded hash of the content.
+	SHA256 string
+	// Path is the path of the temporary file.
+	Path string
+}
+
+// Open opens the temporary file for reading.
+func (f UploadedFile) Open() (*os.File, error) {
+	return os.Open(f.Path)
+}
+
+// supportsFile reports whether a file tag can be set on a field of type t.
+func supportsFile(t reflect.Type) bool {
+	switch t {
+	case fileHeaderType, fileHeadersType, readCloserType, uploadedFileType, uploadedFilesType, uploadedFilePtrType:
+		return true
+	}
+	return reflect.PtrTo(t).Implements(BinaryUnmarshalerType)
+}
+
+func (d *Decoder) extractFile(key string, f *field, e reflect.Value) error {
+	headers, err := d.files(key)
+	if err != nil {
+		d.addParamsReason(key, f.source, message("form.multipart", "error", err.Error()))
+		return nil
+	}
+	if len(headers) == 0 {
+		if f.required {
+			d.addParamsReason(key, f.source, message("form.file_required"))
+		}
+		return nil
+	}
+	d.markProvided(key)
+	for _, header := range headers {
+		header.Filename = SanitizeFilename(header.Filename)
+	}
+	if !d.checkFiles(key, f, headers) {
+		return nil
+	}
+
+	switch e.Type() {
+	case fileHeaderType:
+		e.Set(reflect.ValueOf(headers[0]))
+	case fileHeadersType:
+		e.Set(reflect.ValueOf(headers))
+	case readCloserType:
+		file, err := headers[0].Open()
+		if err != nil {
+			return err
+		}
+		d.onCleanup(func() { file.Close() })
+		e.Set(reflect.ValueOf(file))
+	case uploadedFileType, uploadedFilePtrType:
+		upload, err := d.upload(headers[0])
+		if err != nil {
+			return err
+		}
+		if e.Kind() == reflect.Ptr {
+			e.Set(reflect.ValueOf(&upload))
+		} else {
+			e.Set(reflect.ValueOf(upload))
+		}
+	case uploadedFilesType:
+		uploads := make([]UploadedFile, len(headers))
+		for i, header := range headers {
+			if uploads[i], err = d.upload(header); err != nil {
+				return err
+			}
+		}
+		e.Set(reflect.ValueOf(uploads))
+	default:
+		file, err := headers[0].Open()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		b, err := ioutil.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		if err = e.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(b); err != nil {
+			d.addParamsReason(key, f.source, message("form.unmarshal_binary", "error", err.Error()))
+		}
+	}
+	return nil
+}
+
+// checkFiles checks the files of key against the maxfiles, maxsize and
+// types options, it reports whether they are valid.
+func (d *Decoder) checkFiles(key string, f *field, headers []*multipart.FileHeader) bool {
+	if f.maxFiles > 0 && len(headers) > f.maxFiles {
+		d.addParamsReason(key, f.source, message("form.max_files", "max", f.maxFiles, "count", len(headers)))
+		return false
+	}
+	valid := true
+	for i, header := range headers {
+		field := key
+		if len(headers) > 1 {
+			field = fmt.Sprintf("%s[%d]", key, i)
+		}
+		if f.maxSize > 0 && header.Size > f.maxSize {
+			d.addParamsReason(field, f.source, message("form.file_size", "max", units.Base2Bytes(f.maxSize)))
+			valid = false
+			continue
+		}
+		if len(f.types) == 0 {
+			continue
+		}
+		contentType, err := sniff(header)
+		if err != nil {
+			d.addParamsReason(field, f.source, message("form.file_read", "error", err.Error()))
+			valid = false
+		} else if !matchType(contentType, f.types) {
+			d.addParamsReason(field, f.source, message("form.file_type", "type", contentType, "expected", f.types))
+			valid = false
+		}
+	}
+	return valid
+}
+
+// sniff detects the content type of the file of header from its content,
+// the one given by the client being ignored.
+func sniff(header *multipart.FileHeader) (string, error) {
+	file, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	// http.DetectContentType considers at most 512 bytes
+	b := make([]byte, 512)
+	n, err := io.ReadFull(file, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	contentType := http.DetectContentType(b[:n])
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType, nil
+}
+
+// matchType reports whether contentType is one of types, which can be
+// wildcards such as image/*.
+func matchType(contentType string, types []string) bool {
+	for _, t := range types {
+		if t == contentType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(contentType, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// files returns the file headers of key, the key given in the request being
+// looked up if key has an alias.
+func (d *Decoder) files(key string) ([]*multipart.FileHeader, error) {
+	return d.fileHeaders(d.requestKey(SourceFile, key))
+}
+
+// fileHeaders returns the file headers of key, a request which is not
+// multipart having no files.
+func (d *Decoder) fileHeaders(key string) ([]*multipart.FileHeader, error) {
+	if d.r.MultipartForm == nil {
+		err := d.r.ParseMultipartForm(d.maxMemory())
+		if err == http.ErrNotMultipart {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if d.r.MultipartForm == nil {
+		return nil, nil
+	}
+	if !d.multipartCleanup {
+		d.multipartCleanup = true
+		form := d.r.MultipartForm
+		d.onCleanup(func() { form.RemoveAll() })
+	}
+	return d.r.MultipartForm.File[key], nil
+}
+
+// upload copies the file of header to a temporary file.
+func (d *Decoder) upload(header *multipart.FileHeader) (UploadedFile, error) {
+	upload := UploadedFile{
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+	}
+	src, err := header.Open()
+	if err != nil {
+		return upload, err
+	}
+	defer src.Close()
+	dst, err := ioutil.TempFile(d.TempDir, "upload-")
+	if err != nil {
+		return upload, err
+	}
+	defer dst.Close()
+	d.onCleanup(func() { os.Remove(dst.Name()) })
+
+	hash := sha256.New()
+	if upload.Size, err = io.Copy(io.MultiWriter(dst, hash), src); err != nil {
+		return upload, err
+	}
+	upload.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	upload.Path = dst.Name()
+	return upload, nil
+}
+
+func (d *Decoder) maxMemory() int64 {
+	if d.MaxMemory > 0 {
+		return d.MaxMemory
+	}
+	return defaultMaxMemory
+}
+
+// onCleanup registers fn to be run by Cleanup.
+func (d *Decoder) onCleanup(fn func()) {
+	d.cleanupMu.Lock()
+	defer d.cleanupMu.Unlock()
+	d.cleanups = append(d.cleanups, fn)
+}
+
+// Cleanup closes the files opened while decoding and removes the temporary
+// files, both those of UploadedFile and those created by the multipart
+// parsing. It is called once the request context is done, when the request
+// finishes for a server request, and can be called earlier.
+func (d *Decoder) Cleanup() {
+	d.cleanupMu.Lock()
+	cleanups := d.cleanups
+	d.cleanups = nil
+	d.cleanupMu.Unlock()
+	for _, fn := range cleanups {
+		fn()
+	}
+}
+
+// cleanupOnDone calls Cleanup once the request context is done.
+func (d *Decoder) cleanupOnDone() {
+	done := d.r.Context().Done()
+	if done == nil {
+		// The context is never done, e.g for requests built in tests
+		return
+	}
+	d.cleanupOnce.Do(func() {
+		go func() {
+			<-done
+			d.Cleanup()
+		}()
+	})
+}
+
+// SanitizeFilename returns the base name of a client given filename, without
+// control nor reserved characters, so that it can safely be used on disk.
+func SanitizeFilename(name string) string {
+	name = path.Base(strings.Replace(name, "\\", "/", -1))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, ". ")
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// limitedBody limits the size of a request body, recording whether the
+// limit was exceeded.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errBodyTooLarge
+	}
+	// Read one byte past the limit to know whether it is exceeded
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		b.exceeded = true
+		return int(b.remaining), errBodyTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+var errBodyTooLarge = errors.New("form: request body too large")
+
+// limitBody applies the MaxBodySize of the decoder to the request body. It
+// reports false if the body is known to be too large from its length.
+func (d *Decoder) limitBody() bool {
+	if d.MaxBodySize <= 0 || d.body != nil || d.r.Body == nil {
+		return true
+	}
+	if d.r.ContentLength > d.MaxBodySize {
+		return false
+	}
+	d.body = &limitedBody{ReadCloser: d.r.Body, remaining: d.MaxBodySize}
+	d.r.Body = d.body
+	return true
+}
+
+// bodyTooLarge reports whether the MaxBodySize of the decoder was exceeded.
+func (d *Decoder) bodyTooLarge() bool {
+	return d.body != nil && d.body.exceeded
+}
@@ -0,0 +1,270 @@
+package form_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/sganon/go-request/form"
+	"github.com/sganon/go-request/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+type content []byte
+
+func (c *content) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 {
+		return errors.New("empty content")
+	}
+	*c = b
+	return nil
+}
+
+func (c content) MarshalBinary() ([]byte, error) {
+	return c, nil
+}
+
+type fileInput struct {
+	Avatar   form.UploadedFile       `file:"avatar,required"`
+	Docs     []*multipart.FileHeader `file:"docs"`
+	Raw      io.ReadCloser           `file:"raw"`
+	Content  content                 `file:"content"`
+	Optional *form.UploadedFile      `file:"optional"`
+	Name     string                  `form:"name"`
+}
+
+type part struct {
+	field, filename, content string
+}
+
+func newMultipartRequest(t *testing.T, values map[string]string, parts ...part) *http.Request {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	for key, val := range values {
+		assert.NoError(t, writer.WriteField(key, val))
+	}
+	for _, p := range parts {
+		w, err := writer.CreateFormFile(p.field, p.filename)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(p.content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestFormDecoderFile(t *testing.T) {
+	req := newMultipartRequest(t, map[string]string{"name": "foo"},
+		part{"avatar", "../../etc/passwd", "avatar content"},
+		part{"docs", "a.txt", "a"},
+		part{"docs", `C:\docs\b.txt`, "b"},
+		part{"raw", "raw.bin", "raw content"},
+		part{"content", "content.bin", "binary"},
+	)
+	var output fileInput
+	decoder := form.NewDecoder(req)
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, "foo", output.Name)
+
+	assert.Equal(t, "passwd", output.Avatar.Filename)
+	assert.Equal(t, int64(len("avatar content")), output.Avatar.Size)
+	sum := sha256.Sum256([]byte("avatar content"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), output.Avatar.SHA256)
+	file, err := output.Avatar.Open()
+	if assert.NoError(t, err) {
+		b, _ := ioutil.ReadAll(file)
+		file.Close()
+		assert.Equal(t, "avatar content", string(b))
+	}
+
+	if assert.Len(t, output.Docs, 2) {
+		assert.Equal(t, "a.txt", output.Docs[0].Filename)
+		assert.Equal(t, "b.txt", output.Docs[1].Filename)
+	}
+	if assert.NotNil(t, output.Raw) {
+		b, _ := ioutil.ReadAll(output.Raw)
+		assert.Equal(t, "raw content", string(b))
+	}
+	assert.Equal(t, content("binary"), output.Content)
+	assert.Nil(t, output.Optional)
+
+	decoder.Cleanup()
+	_, err = os.Stat(output.Avatar.Path)
+	assert.True(t, os.IsNotExist(err), "temporary file should have been removed")
+
+	req = newMultipartRequest(t, nil, part{"content", "content.bin", ""})
+	output = fileInput{}
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "avatar", Reason: "this file is required", In: "file", Code: "required", MessageID: "form.file_required"},
+			{Field: "content", Reason: "an error occured via UnmarshalBinary: empty content", In: "file", Code: "invalid", MessageID: "form.unmarshal_binary", Params: map[string]interface{}{"error": "empty content"}},
+		}, decoder.Input.InvalidParams)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	output = fileInput{}
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input, "a request without multipart body should have no files") {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "avatar", Reason: "this file is required", In: "file", Code: "required", MessageID: "form.file_required"},
+		}, decoder.Input.InvalidParams)
+	}
+
+	err = form.Compile(struct {
+		File string `file:"file"`
+	}{})
+	assert.IsType(t, &form.TagError{}, err)
+}
+
+func TestFormDecoderFileCleanupOnDone(t *testing.T) {
+	var path string
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var output fileInput
+		form.NewDecoder(r).Decode(&output)
+		path = output.Avatar.Path
+		_, err := os.Stat(path)
+		assert.NoError(t, err)
+		go func() {
+			<-r.Context().Done()
+			close(done)
+		}()
+	}))
+	defer ts.Close()
+
+	req := newMultipartRequest(t, nil, part{"avatar", "avatar.png", "avatar"})
+	res, err := http.Post(ts.URL, req.Header.Get("Content-Type"), req.Body)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+	<-done
+	removed := false
+	for i := 0; i < 100 && !removed; i++ {
+		_, err := os.Stat(path)
+		removed = os.IsNotExist(err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, removed, "temporary file should be removed once the request is done")
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	assert.Equal(t, "passwd", form.SanitizeFilename("../../etc/passwd"))
+	assert.Equal(t, "b.txt", form.SanitizeFilename(`C:\docs\b.txt`))
+	assert.Equal(t, "ab.txt", form.SanitizeFilename("a\x00<b>.txt"))
+	assert.Equal(t, "file", form.SanitizeFilename(".."))
+	assert.Equal(t, "file", form.SanitizeFilename(""))
+}
+
+const pngContent = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestFormDecoderFileConstraints(t *testing.T) {
+	type input struct {
+		Avatar *multipart.FileHeader   `file:"avatar,maxsize=1KB,types=image/png,image/jpeg"`
+		Docs   []*multipart.FileHeader `file:"docs,maxfiles=2,types=text/*"`
+	}
+	var output input
+	req := newMultipartRequest(t, nil,
+		part{"avatar", "avatar.png", pngContent},
+		part{"docs", "a.txt", "a"},
+		part{"docs", "b.txt", "b"},
+	)
+	decoder := form.NewDecoder(req)
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.NotNil(t, output.Avatar)
+	assert.Len(t, output.Docs, 2)
+
+	tests := []struct {
+		name  string
+		parts []part
+		want  []problem.ParamError
+	}{
+		{
+			name:  "too large",
+			parts: []part{{"avatar", "avatar.png", pngContent + strings.Repeat("a", 1024)}},
+			want:  []problem.ParamError{{Field: "avatar", Reason: "file size must not exceed 1KiB", In: "file", Code: "out_of_range", MessageID: "form.file_size", Params: map[string]interface{}{"max": units.Base2Bytes(1024)}}},
+		},
+		{
+			name:  "sniffed type",
+			parts: []part{{"avatar", "avatar.png", "<html><body></body></html>"}},
+			want: []problem.ParamError{{
+				Field:  "avatar",
+				Reason: "file type text/html is not allowed, expected one of: image/png, image/jpeg",
+				In:     "file", Code: "invalid_value", MessageID: "form.file_type", Params: map[string]interface{}{"type": "text/html", "expected": []string{"image/png", "image/jpeg"}},
+			}},
+		},
+		{
+			name:  "too many files",
+			parts: []part{{"docs", "a.txt", "a"}, {"docs", "b.txt", "b"}, {"docs", "c.txt", "c"}},
+			want:  []problem.ParamError{{Field: "docs", Reason: "expected at most 2 files, got 3", In: "file", Code: "length", MessageID: "form.max_files", Params: map[string]interface{}{"max": 2, "count": 3}}},
+		},
+		{
+			name:  "wildcard type",
+			parts: []part{{"docs", "a.txt", "a"}, {"docs", "b.png", pngContent}},
+			want: []problem.ParamError{{
+				Field:  "docs[1]",
+				Reason: "file type image/png is not allowed, expected one of: text/*",
+				In:     "file", Code: "invalid_value", MessageID: "form.file_type", Params: map[string]interface{}{"type": "image/png", "expected": []string{"text/*"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output input
+			decoder := form.NewDecoder(newMultipartRequest(t, nil, tt.parts...))
+			decoder.Decode(&output)
+			if assert.NotNil(t, decoder.Input) {
+				assert.Equal(t, tt.want, decoder.Input.InvalidParams)
+			}
+			assert.Nil(t, output.Avatar)
+			assert.Nil(t, output.Docs)
+		})
+	}
+
+	err := form.Compile(struct {
+		File *multipart.FileHeader `file:"file,maxsize=big"`
+	}{})
+	if assert.IsType(t, &form.TagError{}, err) {
+		assert.Equal(t, "maxsize", err.(*form.TagError).Option)
+	}
+}
+
+func TestFormDecoderMaxBodySize(t *testing.T) {
+	var output fileInput
+	req := newMultipartRequest(t, nil, part{"avatar", "avatar.bin", strings.Repeat("a", 1024)})
+	err := form.NewDecoder(req, form.WithMaxBodySize(512)).Decode(&output)
+	if assert.IsType(t, &problem.PayloadTooLargeProblem{}, err) {
+		assert.Equal(t, problem.DefaultPayloadTooLarge.Title, err.(*problem.PayloadTooLargeProblem).Title)
+	}
+
+	// Without Content-Length the limit is detected while reading the body
+	req = newMultipartRequest(t, nil, part{"avatar", "avatar.bin", strings.Repeat("a", 1024)})
+	req.ContentLength = -1
+	err = form.NewDecoder(req, form.WithMaxBodySize(512)).Decode(&output)
+	assert.IsType(t, &problem.PayloadTooLargeProblem{}, err)
+
+	req = newMultipartRequest(t, nil, part{"avatar", "avatar.bin", "avatar"})
+	req.ContentLength = -1
+	decoder := form.NewDecoder(req, form.WithMaxBodySize(1024))
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, "avatar.bin", output.Avatar.Filename)
+	decoder.Cleanup()
+}
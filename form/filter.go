@@ -0,0 +1,584 @@
+package form
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sganon/go-request/problem"
+)
+
+var filterType = reflect.TypeOf(Filter{})
+
+// Filter operators, also used in the filterable option, e.g
+// `form:"filter,filterable=age:gt|lte,name:eq|like"`. The range operators
+// OpGreaterThan, OpGreaterThanOrEqual, OpLessThan and OpLessThanOrEqual
+// are written >, >=, < and <= in expressions.
+const (
+	OpEqual    = "eq"   // =
+	OpNotEqual = "ne"   // !=
+	OpLike     = "like" // ~, * matching any characters
+	OpIn       = "in"   // in (a,b)
+)
+
+// filterSymbols maps the symbols of the comparisons to their operator.
+var filterSymbols = map[string]string{
+	"=":  OpEqual,
+	"!=": OpNotEqual,
+	">":  OpGreaterThan,
+	">=": OpGreaterThanOrEqual,
+	"<":  OpLessThan,
+	"<=": OpLessThanOrEqual,
+	"~":  OpLike,
+}
+
+// maxFilterDepth bounds the nesting of parentheses and not of an expression.
+const maxFilterDepth = 32
+
+// Filter is a boolean expression decoded from a query such as
+// ?filter=age>=30 and (name~"bo*" or status in (active,pending)). Comparisons
+// use =, !=, >, >=, <, <=, ~ (like) and in, and are combined with and, or, not
+// and parentheses. Values are words or double quoted strings. The filterable
+// option lists the fields which can be filtered, and optionally their
+// operators: `form:"filter,filterable=age:gt|gte,name,status:in"`.
+type Filter struct {
+	// Expr is the root of the expression, nil if no filter is given.
+	Expr FilterExpr
+}
+
+// ParseFilter parses a filter expression, see Filter. Syntax errors are
+// *FilterSyntaxError.
+func ParseFilter(s string) (Filter, error) {
+	if strings.TrimSpace(s) == "" {
+		return Filter{}, nil
+	}
+	p := &filterParser{input: s}
+	p.next()
+	expr, err := p.parseOr()
+	if err != nil {
+		return Filter{}, err
+	}
+	if p.tok.kind != tokenEOF {
+		return Filter{}, p.errorf("form.filter_unexpected", "token", p.tok.text)
+	}
+	return Filter{Expr: expr}, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (f *Filter) UnmarshalText(text []byte) error {
+	filter, err := ParseFilter(string(text))
+	if err != nil {
+		return err
+	}
+	*f = filter
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (f Filter) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// String returns the expression of f, parsing back to f.
+func (f Filter) String() string {
+	if f.Expr == nil {
+		return ""
+	}
+	return f.Expr.String()
+}
+
+// Accept calls v with the root of f, see FilterVisitor. It does nothing if f
+// is empty.
+func (f Filter) Accept(v FilterVisitor) error {
+	if f.Expr == nil {
+		return nil
+	}
+	return f.Expr.Accept(v)
+}
+
+// FilterExpr is a node of a Filter: *AndExpr, *OrExpr, *NotExpr or *Comparison.
+type FilterExpr interface {
+	// Accept calls the method of v visiting the node.
+	Accept(v FilterVisitor) error
+	String() string
+}
+
+// FilterVisitor visits the nodes of a Filter, for instance to compile it to
+// SQL or to an in-memory predicate. Visiting the children of a node is left
+// to the visitor, calling their Accept method.
+type FilterVisitor interface {
+	VisitAnd(e *AndExpr) error
+	VisitOr(e *OrExpr) error
+	VisitNot(e *NotExpr) error
+	VisitComparison(e *Comparison) error
+}
+
+// AndExpr is true if all of its Exprs are.
+type AndExpr struct {
+	Exprs []FilterExpr
+}
+
+// Accept implements FilterExpr
+func (e *AndExpr) Accept(v FilterVisitor) error {
+	return v.VisitAnd(e)
+}
+
+func (e *AndExpr) String() string {
+	items := make([]string, len(e.Exprs))
+	for i, expr := range e.Exprs {
+		items[i] = expr.String()
+		if _, ok := expr.(*OrExpr); ok {
+			items[i] = "(" + items[i] + ")"
+		}
+	}
+	return strings.Join(items, " and ")
+}
+
+// OrExpr is true if one of its Exprs is.
+type OrExpr struct {
+	Exprs []FilterExpr
+}
+
+// Accept implements FilterExpr
+func (e *OrExpr) Accept(v FilterVisitor) error {
+	return v.VisitOr(e)
+}
+
+func (e *OrExpr) String() string {
+	items := make([]string, len(e.Exprs))
+	for i, expr := range e.Exprs {
+		items[i] = expr.String()
+	}
+	return strings.Join(items, " or ")
+}
+
+// NotExpr negates its Expr.
+type NotExpr struct {
+	Expr FilterExpr
+}
+
+// Accept implements FilterExpr
+func (e *NotExpr) Accept(v FilterVisitor) error {
+	return v.VisitNot(e)
+}
+
+func (e *NotExpr) String() string {
+	if _, ok := e.Expr.(*Comparison); ok {
+		return "not " + e.Expr.String()
+	}
+	return "not (" + e.Expr.String() + ")"
+}
+
+// Comparison compares a field to its values, a single one unless Op is OpIn.
+// Values are not converted, their type being known by the visitors.
+type Comparison struct {
+	Field  string
+	Op     string
+	Values []string
+	// Column is the 1-based column of Field in the expression, in runes.
+	Column int
+}
+
+// Accept implements FilterExpr
+func (e *Comparison) Accept(v FilterVisitor) error {
+	return v.VisitComparison(e)
+}
+
+func (e *Comparison) String() string {
+	values := make([]string, len(e.Values))
+	for i, val := range e.Values {
+		values[i] = quoteFilterValue(val)
+	}
+	if e.Op == OpIn {
+		return e.Field + " in (" + strings.Join(values, ",") + ")"
+	}
+	for symbol, op := range filterSymbols {
+		if op == e.Op {
+			return e.Field + symbol + strings.Join(values, ",")
+		}
+	}
+	return e.Field + " " + e.Op + " " + strings.Join(values, ",")
+}
+
+// quoteFilterValue quotes val unless it is a word.
+func quoteFilterValue(val string) string {
+	if val == "" || isFilterKeyword(val) || strings.IndexFunc(val, func(r rune) bool { return !isWordRune(r) }) >= 0 {
+		return strconv.Quote(val)
+	}
+	return val
+}
+
+// FilterSyntaxError is returned when a filter expression cannot be parsed.
+type FilterSyntaxError struct {
+	// Column is the 1-based column of the error in the expression, in runes.
+	Column int
+	Msg    string
+	reason *problem.Message
+}
+
+func (e *FilterSyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at column %d: %s", e.Column, e.Msg)
+}
+
+// message returns the error as a localizable message.
+func (e *FilterSyntaxError) message() *problem.Message {
+	return message("form.filter_syntax", "column", e.Column, "reason", e.reason)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenSymbol
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+	// col is the 1-based column of the token, in runes.
+	col int
+}
+
+// filterParser is a recursive descent parser of the grammar:
+//
+//	or         = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" or ")" | comparison
+//	comparison = field symbol value | field "in" "(" value { "," value } ")"
+type filterParser struct {
+	input string
+	pos   int
+	// runes is the number of runes of input[:counted], columns being counted
+	// in runes from the previous token.
+	runes, counted int
+	depth          int
+	tok            filterToken
+	err            error
+}
+
+// errorf returns the syntax error of the message id at the current token,
+// unless the lexer already failed.
+func (p *filterParser) errorf(id string, params ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	reason := message(id, params...)
+	return &FilterSyntaxError{Column: p.tok.col, Msg: reason.Error(), reason: reason}
+}
+
+// next reads the next token onto p.tok.
+func (p *filterParser) next() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+	start := p.pos
+	p.runes += utf8.RuneCountInString(p.input[p.counted:start])
+	p.counted = start
+	p.tok = filterToken{col: p.runes + 1}
+	if p.pos >= len(p.input) {
+		p.tok.kind = tokenEOF
+		return
+	}
+	switch c := p.input[p.pos]; c {
+	case '(':
+		p.tok.kind, p.tok.text = tokenLParen, "("
+		p.pos++
+	case ')':
+		p.tok.kind, p.tok.text = tokenRParen, ")"
+		p.pos++
+	case ',':
+		p.tok.kind, p.tok.text = tokenComma, ","
+		p.pos++
+	case '"':
+		p.readString()
+	case '=', '!', '<', '>', '~':
+		p.pos++
+		if p.pos < len(p.input) && p.input[p.pos] == '=' && c != '=' && c != '~' {
+			p.pos++
+		}
+		p.tok.kind, p.tok.text = tokenSymbol, p.input[start:p.pos]
+		if _, ok := filterSymbols[p.tok.text]; !ok {
+			p.err = p.errorf("form.filter_unexpected", "token", p.tok.text)
+		}
+	default:
+		for p.pos < len(p.input) {
+			r := rune(p.input[p.pos])
+			if r < unicode.MaxASCII && !isWordRune(r) {
+				break
+			}
+			p.pos++
+		}
+		if p.pos == start {
+			p.pos++
+			p.err = p.errorf("form.filter_unexpected", "token", p.input[start:p.pos])
+		}
+		p.tok.kind, p.tok.text = tokenWord, p.input[start:p.pos]
+	}
+}
+
+// readString reads a double quoted string, \" and \\ being escaped.
+func (p *filterParser) readString() {
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.input); p.pos++ {
+		c := p.input[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.input):
+			p.pos++
+			b.WriteByte(p.input[p.pos])
+		case c == '"':
+			p.pos++
+			p.tok.kind, p.tok.text = tokenString, b.String()
+			return
+		default:
+			b.WriteByte(c)
+		}
+	}
+	p.err = p.errorf("form.filter_string")
+}
+
+// isWordRune reports whether r can be part of a field or a value without quotes.
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(`()<>=!~,"\`, r)
+}
+
+func isFilterKeyword(word string) bool {
+	switch strings.ToLower(word) {
+	case "and", "or", "not", OpIn:
+		return true
+	}
+	return false
+}
+
+// keyword reports whether the current token is the keyword kw.
+func (p *filterParser) keyword(kw string) bool {
+	return p.tok.kind == tokenWord && strings.EqualFold(p.tok.text, kw)
+}
+
+func (p *filterParser) parseOr() (FilterExpr, error) {
+	expr, err := p.parseAnd()
+	if err != nil || !p.keyword("or") {
+		return expr, err
+	}
+	or := &OrExpr{Exprs: []FilterExpr{expr}}
+	for p.keyword("or") {
+		p.next()
+		if expr, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		or.Exprs = append(or.Exprs, expr)
+	}
+	return or, nil
+}
+
+func (p *filterParser) parseAnd() (FilterExpr, error) {
+	expr, err := p.parseUnary()
+	if err != nil || !p.keyword("and") {
+		return expr, err
+	}
+	and := &AndExpr{Exprs: []FilterExpr{expr}}
+	for p.keyword("and") {
+		p.next()
+		if expr, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		and.Exprs = append(and.Exprs, expr)
+	}
+	return and, nil
+}
+
+func (p *filterParser) parseUnary() (FilterExpr, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.keyword("not") || p.tok.kind == tokenLParen {
+		if p.depth++; p.depth > maxFilterDepth {
+			return nil, p.errorf("form.filter_depth")
+		}
+		defer func() { p.depth-- }()
+	}
+	switch {
+	case p.keyword("not"):
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: expr}, nil
+	case p.tok.kind == tokenLParen:
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenRParen {
+			return nil, p.errorf("form.filter_expected", "token", ")")
+		}
+		p.next()
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (FilterExpr, error) {
+	if p.tok.kind != tokenWord || isFilterKeyword(p.tok.text) {
+		return nil, p.errorf("form.filter_name")
+	}
+	c := &Comparison{Field: p.tok.text, Column: p.tok.col}
+	p.next()
+	switch {
+	case p.keyword(OpIn):
+		c.Op = OpIn
+		p.next()
+		if p.tok.kind != tokenLParen {
+			return nil, p.errorf("form.filter_expected", "token", "(")
+		}
+		for {
+			p.next()
+			val, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			c.Values = append(c.Values, val)
+			if p.tok.kind != tokenComma {
+				break
+			}
+		}
+		if p.tok.kind != tokenRParen {
+			return nil, p.errorf("form.filter_list")
+		}
+		p.next()
+	case p.tok.kind == tokenSymbol:
+		c.Op = filterSymbols[p.tok.text]
+		p.next()
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		c.Values = []string{val}
+	default:
+		return nil, p.errorf("form.filter_op")
+	}
+	return c, p.err
+}
+
+func (p *filterParser) parseValue() (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	if p.tok.kind != tokenWord && p.tok.kind != tokenString {
+		return "", p.errorf("form.filter_value")
+	}
+	val := p.tok.text
+	p.next()
+	return val, nil
+}
+
+// filterChecker checks the comparisons of a filter against the filterable
+// option, mapping the fields to their allowed operators, all if nil.
+type filterChecker map[string][]string
+
+func (c filterChecker) VisitAnd(e *AndExpr) error {
+	return c.visitAll(e.Exprs)
+}
+
+func (c filterChecker) VisitOr(e *OrExpr) error {
+	return c.visitAll(e.Exprs)
+}
+
+func (c filterChecker) VisitNot(e *NotExpr) error {
+	return e.Expr.Accept(c)
+}
+
+func (c filterChecker) visitAll(exprs []FilterExpr) error {
+	for _, expr := range exprs {
+		if err := expr.Accept(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c filterChecker) VisitComparison(e *Comparison) error {
+	ops, ok := c[e.Field]
+	if !ok {
+		fields := make([]string, 0, len(c))
+		for field := range c {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		return message("form.filter_field", "field", e.Field, "column", e.Column, "expected", fields)
+	}
+	if ops != nil && !contains(ops, e.Op) {
+		return message("form.filter_operator", "operator", e.Op, "column", e.Column, "field", e.Field, "expected", ops)
+	}
+	return nil
+}
+
+// convertFilter converts val if e is a Filter, checking it against the
+// filterable option, it reports whether e is a Filter.
+func convertFilter(e reflect.Value, val string, f *field) (bool, error) {
+	if e.Type() != filterType {
+		return false, nil
+	}
+	filter, err := ParseFilter(val)
+	if syntaxErr, ok := err.(*FilterSyntaxError); ok {
+		return true, syntaxErr.message()
+	} else if err != nil {
+		return true, err
+	}
+	if f.filterable != nil {
+		if err := filter.Accept(f.filterable); err != nil {
+			return true, err
+		}
+	}
+	e.Set(reflect.ValueOf(filter))
+	return true, nil
+}
+
+// compileFilterOptions parses the filterable option, only accepted by Filter fields.
+func (f *field) compileFilterOptions(t reflect.Type) error {
+	filterable, ok := f.opts.Get("filterable")
+	if !ok {
+		return nil
+	}
+	if indirectType(t) != filterType {
+		return &TagError{Option: "filterable", Err: fmt.Errorf("only form.Filter accepts the filterable option, got %s", t)}
+	}
+	f.filterable = filterChecker{}
+	for _, item := range strings.Split(filterable, ",") {
+		i := strings.Index(item, ":")
+		if i < 0 {
+			f.filterable[item] = nil
+			continue
+		}
+		ops := strings.Split(item[i+1:], "|")
+		for _, op := range ops {
+			if !isFilterOp(op) {
+				return &TagError{Option: "filterable", Err: fmt.Errorf("unknown operator %q", op)}
+			}
+		}
+		f.filterable[item[:i]] = ops
+	}
+	return nil
+}
+
+func isFilterOp(op string) bool {
+	if op == OpIn {
+		return true
+	}
+	for _, symbolOp := range filterSymbols {
+		if op == symbolOp {
+			return true
+		}
+	}
+	return false
+}
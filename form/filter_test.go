@@ -0,0 +1,247 @@
+package form_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sganon/go-request/form"
+	"github.com/sganon/go-request/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+		err  string
+	}{
+		{expr: `age>=30 and (name~"bo*" or status in (active,pending))`, want: `age>=30 and (name~bo* or status in (active,pending))`},
+		{expr: `a=1 or b=2 and c=3`, want: `a=1 or b=2 and c=3`},
+		{expr: `NOT (a!=1 OR b<2)`, want: `not (a!=1 or b<2)`},
+		{expr: `not a = "x \"y\""`, want: `not a="x \"y\""`},
+		{expr: `name = "and"`, want: `name="and"`},
+		{expr: `age>=`, err: "syntax error at column 6: expected a value"},
+		{expr: `age 30`, err: "syntax error at column 5: expected an operator such as =, !=, >, >=, <, <=, ~ or in"},
+		{expr: `(age>1`, err: "syntax error at column 7: expected )"},
+		{expr: `age>1)`, err: `syntax error at column 6: unexpected ")"`},
+		{expr: `status in (a,`, err: "syntax error at column 14: expected a value"},
+		{expr: `name="bob`, err: "syntax error at column 6: unterminated string"},
+		{expr: `age!30`, err: `syntax error at column 4: unexpected "!"`},
+		{expr: `and=1`, err: "syntax error at column 1: expected a field"},
+		{expr: `é=1 and b!1`, err: `syntax error at column 10: unexpected "!"`},
+		{expr: strings.Repeat("(", 40) + "a=1" + strings.Repeat(")", 40), err: "syntax error at column 33: expression is nested too deeply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := form.ParseFilter(tt.expr)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, filter.String())
+		})
+	}
+}
+
+type filterInput struct {
+	Filter form.Filter `query:"filter,filterable=age:gt|gte|lt|lte,name:eq|like,status"`
+}
+
+func TestFilterDecode(t *testing.T) {
+	tests := []struct {
+		filter string
+		err    string
+		code   string
+		id     string
+		params map[string]interface{}
+	}{
+		{filter: `age>=30 and (name~bo* or status in (active,pending))`},
+		{filter: `password="x"`, err: `field "password" at column 1 cannot be filtered, expected one of: age, name, status`, code: "invalid_value", id: "form.filter_field", params: map[string]interface{}{"field": "password", "column": 1, "expected": []string{"age", "name", "status"}}},
+		{filter: `age>1 and name!=bob`, err: `operator ne at column 11 is not allowed on "name", expected one of: eq, like`, code: "invalid_value", id: "form.filter_operator", params: map[string]interface{}{"operator": "ne", "column": 11, "field": "name", "expected": []string{"eq", "like"}}},
+		{filter: `name="é" and é=1`, err: `field "é" at column 14 cannot be filtered, expected one of: age, name, status`, code: "invalid_value", id: "form.filter_field", params: map[string]interface{}{"field": "é", "column": 14, "expected": []string{"age", "name", "status"}}},
+		{filter: `age>`, err: "syntax error at column 5: expected a value", code: "invalid_format", id: "form.filter_syntax", params: map[string]interface{}{"column": 5, "reason": &problem.Message{ID: "form.filter_value", Code: "invalid_format"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			var output filterInput
+			decoder := form.NewDecoder(httptest.NewRequest("GET", "/?filter="+url.QueryEscape(tt.filter), nil))
+			decoder.Decode(&output)
+			if tt.err != "" {
+				if assert.NotNil(t, decoder.Input) {
+					assert.Equal(t, []problem.ParamError{{Field: "filter", Reason: tt.err, In: "query", Code: tt.code, MessageID: tt.id, Params: tt.params}}, decoder.Input.InvalidParams)
+				}
+				return
+			}
+			assert.Nil(t, decoder.Input)
+			assert.Equal(t, tt.filter, output.Filter.String())
+
+			values, err := form.NewEncoder().Encode(output)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.filter, values.Get("filter"))
+		})
+	}
+
+	assert.Error(t, form.Compile(struct {
+		Filter form.Filter `query:"filter,filterable=age:between"`
+	}{}), "operators should be known")
+	assert.Error(t, form.Compile(struct {
+		Filter string `query:"filter,filterable=age"`
+	}{}), "only form.Filter accepts the filterable option")
+}
+
+// sqlCompiler compiles a filter to a SQL condition with ? placeholders.
+type sqlCompiler struct {
+	columns map[string]string
+	b       strings.Builder
+	args    []interface{}
+}
+
+var sqlOperators = map[string]string{
+	form.OpEqual: "=", form.OpNotEqual: "<>", form.OpLike: "LIKE",
+	form.OpGreaterThan: ">", form.OpGreaterThanOrEqual: ">=", form.OpLessThan: "<", form.OpLessThanOrEqual: "<=",
+}
+
+func (c *sqlCompiler) VisitAnd(e *form.AndExpr) error { return c.join(e.Exprs, " AND ") }
+func (c *sqlCompiler) VisitOr(e *form.OrExpr) error   { return c.join(e.Exprs, " OR ") }
+
+func (c *sqlCompiler) VisitNot(e *form.NotExpr) error {
+	c.b.WriteString("NOT ")
+	return c.join([]form.FilterExpr{e.Expr}, "")
+}
+
+func (c *sqlCompiler) join(exprs []form.FilterExpr, sep string) error {
+	c.b.WriteString("(")
+	for i, expr := range exprs {
+		if i > 0 {
+			c.b.WriteString(sep)
+		}
+		if err := expr.Accept(c); err != nil {
+			return err
+		}
+	}
+	c.b.WriteString(")")
+	return nil
+}
+
+func (c *sqlCompiler) VisitComparison(e *form.Comparison) error {
+	column, ok := c.columns[e.Field]
+	if !ok {
+		return fmt.Errorf("unknown field %s", e.Field)
+	}
+	for _, val := range e.Values {
+		if e.Op == form.OpLike {
+			val = strings.Replace(val, "*", "%", -1)
+		}
+		c.args = append(c.args, val)
+	}
+	if e.Op == form.OpIn {
+		fmt.Fprintf(&c.b, "%s IN (?%s)", column, strings.Repeat(", ?", len(e.Values)-1))
+		return nil
+	}
+	fmt.Fprintf(&c.b, "%s %s ?", column, sqlOperators[e.Op])
+	return nil
+}
+
+// predicate compiles a filter to an in-memory predicate on maps.
+type predicate struct {
+	match func(item map[string]string) bool
+}
+
+func (p *predicate) compile(expr form.FilterExpr) func(map[string]string) bool {
+	expr.Accept(p)
+	return p.match
+}
+
+func (p *predicate) VisitAnd(e *form.AndExpr) error {
+	matches := make([]func(map[string]string) bool, len(e.Exprs))
+	for i, expr := range e.Exprs {
+		matches[i] = p.compile(expr)
+	}
+	p.match = func(item map[string]string) bool {
+		for _, match := range matches {
+			if !match(item) {
+				return false
+			}
+		}
+		return true
+	}
+	return nil
+}
+
+func (p *predicate) VisitOr(e *form.OrExpr) error {
+	matches := make([]func(map[string]string) bool, len(e.Exprs))
+	for i, expr := range e.Exprs {
+		matches[i] = p.compile(expr)
+	}
+	p.match = func(item map[string]string) bool {
+		for _, match := range matches {
+			if match(item) {
+				return true
+			}
+		}
+		return false
+	}
+	return nil
+}
+
+func (p *predicate) VisitNot(e *form.NotExpr) error {
+	match := p.compile(e.Expr)
+	p.match = func(item map[string]string) bool { return !match(item) }
+	return nil
+}
+
+func (p *predicate) VisitComparison(e *form.Comparison) error {
+	p.match = func(item map[string]string) bool {
+		v := item[e.Field]
+		switch e.Op {
+		case form.OpIn:
+			for _, val := range e.Values {
+				if v == val {
+					return true
+				}
+			}
+			return false
+		case form.OpLike:
+			return strings.HasPrefix(v, strings.TrimSuffix(e.Values[0], "*"))
+		case form.OpEqual:
+			return v == e.Values[0]
+		}
+		n, _ := strconv.Atoi(v)
+		val, _ := strconv.Atoi(e.Values[0])
+		switch e.Op {
+		case form.OpGreaterThan:
+			return n > val
+		case form.OpGreaterThanOrEqual:
+			return n >= val
+		case form.OpLessThan:
+			return n < val
+		case form.OpLessThanOrEqual:
+			return n <= val
+		}
+		return v != e.Values[0]
+	}
+	return nil
+}
+
+func TestFilterVisitor(t *testing.T) {
+	filter, err := form.ParseFilter(`age>=30 and (name~"bo*" or not status in (active,pending))`)
+	assert.NoError(t, err)
+
+	c := &sqlCompiler{columns: map[string]string{"age": "u.age", "name": "u.name", "status": "u.status"}}
+	assert.NoError(t, filter.Accept(c))
+	assert.Equal(t, "(u.age >= ? AND (u.name LIKE ? OR NOT (u.status IN (?, ?))))", c.b.String())
+	assert.Equal(t, []interface{}{"30", "bo%", "active", "pending"}, c.args)
+
+	match := new(predicate).compile(filter.Expr)
+	assert.True(t, match(map[string]string{"age": "31", "name": "bob", "status": "active"}))
+	assert.True(t, match(map[string]string{"age": "30", "name": "alice", "status": "closed"}))
+	assert.False(t, match(map[string]string{"age": "30", "name": "alice", "status": "active"}))
+	assert.False(t, match(map[string]string{"age": "29", "name": "bob"}))
+
+	assert.NoError(t, form.Filter{}.Accept(c), "an empty filter should not be visited")
+}
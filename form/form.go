@@ -1,105 +1,388 @@
 package form
 
 import (
-	"encoding"
-	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 	"reflect"
-	"strconv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/fatih/structtag"
 	"github.com/sganon/go-request/problem"
 )
 
 const (
 	mb = 1 << 20
+	// defaultMaxMemory is the one used by http.Request.FormValue
+	defaultMaxMemory = 32 * mb
 )
 
 // Decoder handles unmarshalling and validation of its request's query
 type Decoder struct {
 	r              *http.Request
+	values         map[string]url.Values
+	provided       map[string]bool
+	lookups        map[string]map[string]bool
+	converters     map[reflect.Type]Converter
+	convertersKey  string
+	aliased        map[aliasKey]string
 	BoolStrictMode bool
-	Input          *problem.Input
+	// BoolValues is the vocabulary accepted for booleans, matched case
+	// insensitively. When nil strconv.ParseBool is used, see ExtendedBoolValues.
+	BoolValues map[string]bool
+	// Location is the default location of decoded times, UTC if nil.
+	Location *time.Location
+	// LocationHeader is the name of a request header, such as Time-Zone,
+	// holding the location of decoded times when they have no tz option.
+	LocationHeader string
+	// PathParams gives the parameters of path tags, ServeMuxParams if nil.
+	PathParams PathParamSource
+	// PathErrorStatus is the status of the problem returned when a path
+	// parameter is invalid: http.StatusNotFound gives a not found problem,
+	// any other value an input problem.
+	PathErrorStatus int
+	// MaxMemory is the maximum size of a multipart body stored in memory,
+	// files being stored on disk beyond it. Defaults to 32 MB.
+	MaxMemory int64
+	// MaxBodySize is the maximum size of the request body, a larger body
+	// giving a payload too large problem. No limit is applied if zero.
+	MaxBodySize int64
+	// DisallowUnknownParams makes the decoder reject the query and body
+	// keys which are not declared by the decoded struct, a close declared
+	// key being suggested.
+	DisallowUnknownParams bool
+	// AllowedParams are the keys accepted with DisallowUnknownParams even
+	// if not declared, such as tracing parameters. A key ending with a *
+	// matches a prefix, e.g utm_*.
+	AllowedParams []string
+	// TagName is the name of the tag of the form source, form if empty, so
+	// that structs with tags such as schema can be decoded.
+	TagName string
+	// Naming derives the keys of the fields without tag, which are then
+	// read from the form source. Such fields are skipped with NoNaming.
+	Naming NamingStrategy
+	// CursorKey signs the cursors of Pagination fields, cursors being
+	// rejected if empty. It must be kept secret.
+	CursorKey []byte
+	// TempDir is the directory of the temporary files of UploadedFile,
+	// the default one if empty.
+	TempDir string
+	Input   *problem.Input
+
+	body             *limitedBody
+	headerLoc        *time.Location
+	headerLocOK      bool
+	multipartCleanup bool
+	cleanups         []func()
+	cleanupMu        sync.Mutex
+	cleanupOnce      sync.Once
+}
+
+// Option configures a Decoder, options allow to configure the decoder used
+// by request.Decode.
+type Option func(*Decoder)
+
+// WithTagName sets the TagName of the decoder.
+func WithTagName(name string) Option {
+	return func(d *Decoder) {
+		d.TagName = name
+	}
+}
+
+// WithNaming sets the Naming of the decoder.
+func WithNaming(naming NamingStrategy) Option {
+	return func(d *Decoder) {
+		d.Naming = naming
+	}
+}
+
+// WithPathParams sets the PathParamSource of the decoder.
+func WithPathParams(source PathParamSource) Option {
+	return func(d *Decoder) {
+		d.PathParams = source
+	}
+}
+
+// WithPathErrorStatus sets the PathErrorStatus of the decoder.
+func WithPathErrorStatus(status int) Option {
+	return func(d *Decoder) {
+		d.PathErrorStatus = status
+	}
+}
+
+// WithMaxBodySize sets the MaxBodySize of the decoder.
+func WithMaxBodySize(size int64) Option {
+	return func(d *Decoder) {
+		d.MaxBodySize = size
+	}
+}
+
+// WithCursorKey sets the CursorKey of the decoder.
+func WithCursorKey(key []byte) Option {
+	return func(d *Decoder) {
+		d.CursorKey = key
+	}
+}
+
+// WithDisallowUnknownParams sets DisallowUnknownParams, allowed being the
+// AllowedParams of the decoder.
+func WithDisallowUnknownParams(allowed ...string) Option {
+	return func(d *Decoder) {
+		d.DisallowUnknownParams = true
+		d.AllowedParams = allowed
+	}
 }
 
 // NewDecoder return a pointer to a new decoder
-func NewDecoder(r *http.Request) *Decoder {
-	return &Decoder{
-		r:              r,
-		BoolStrictMode: true,
+func NewDecoder(r *http.Request, opts ...Option) *Decoder {
+	d := &Decoder{
+		r:               r,
+		BoolStrictMode:  true,
+		PathErrorStatus: http.StatusBadRequest,
+	}
+	for _, opt := range opts {
+		opt(d)
 	}
+	return d
 }
 
 // Decode input data from its request and stores it onto i.
 func (d *Decoder) Decode(v interface{}) error {
 	elem := reflect.ValueOf(v).Elem()
-	// Loop through each fields of v checking for correct input rules.
-	for i := 0; i < elem.NumField(); i++ {
-		fieldTag := elem.Type().Field(i).Tag
+	p, err := planOf(elem.Type(), d.planConfig())
+	if err != nil {
+		return err
+	}
+	if !d.limitBody() {
+		prob := problem.DefaultPayloadTooLarge
+		return &prob
+	}
+	err = d.decodeStruct(elem, p, "")
+	if len(d.cleanups) > 0 {
+		d.cleanupOnDone()
+	}
+	if d.bodyTooLarge() {
+		prob := problem.DefaultPayloadTooLarge
+		return &prob
+	}
+	if err != nil {
+		return err
+	}
+	if d.DisallowUnknownParams {
+		d.checkUnknownParams()
+	}
+	if d.PathErrorStatus == http.StatusNotFound && d.hasPathError() {
+		prob := problem.DefaultNotFound
+		return &prob
+	}
+	return d.Input
+}
 
-		tags, err := structtag.Parse(string(fieldTag))
+// decodeStruct decodes the fields of elem following its plan, prefix being
+// the key of elem when it is nested into another struct.
+func (d *Decoder) decodeStruct(elem reflect.Value, p *plan, prefix string) error {
+	for _, f := range p.fields {
+		e, ok := d.fieldOf(elem, p, f, prefix)
+		if !ok {
+			continue
+		}
+		key := f.name
+		if isKeyed(f.source) {
+			key = joinKey(prefix, f.name)
+		}
+		d.resolveAlias(prefix, key, f)
+		var err error
+		switch f.kind {
+		case nestedField:
+			err = d.decodeNested(e, f, key)
+		case sliceField:
+			err = d.extractSlice(key, f, e)
+		case mapField:
+			err = d.extractMap(key, f, e)
+		case valuesField:
+			err = d.extractValues(key, f, e)
+		case paginationField:
+			err = d.extractPagination(key, f, e)
+		case fileField:
+			err = d.extractFile(key, f, e)
+		default:
+			err = d.extractForm(key, f, e)
+		}
 		if err != nil {
-			return problem.DefaultUnexpected
+			return err
 		}
-		queryTag, noFormErr := tags.Get("form")
-		fileTag, noFileErr := tags.Get("file")
-		if noFormErr != nil && noFileErr != nil {
-			// Skip if field has no input tag
-			continue
+	}
+	return nil
+}
+
+// fieldOf returns the field f of elem. The embedded pointers on its path are
+// allocated when one of their fields is given, as pointers to nested structs,
+// it reports false if f is within an embedded pointer which is not given.
+func (d *Decoder) fieldOf(elem reflect.Value, p *plan, f *field, prefix string) (reflect.Value, bool) {
+	v := elem.Field(f.index[0])
+	for i, x := range f.index[1:] {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !d.embeddedGiven(p, f.index[:i+1], prefix) {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
 		}
+		v = v.Field(x)
+	}
+	return v, true
+}
 
-		if queryTag != nil {
-			d.extractForm(queryTag.Name, queryTag.HasOption("required"), elem.Field(i))
+// embeddedGiven reports whether a field of the embedded struct at index is
+// given in the request.
+func (d *Decoder) embeddedGiven(p *plan, index []int, prefix string) bool {
+	for _, f := range p.fields {
+		if len(f.index) <= len(index) || !reflect.DeepEqual(f.index[:len(index)], index) {
+			continue
 		}
-		if fileTag != nil {
-			if err := d.r.ParseMultipartForm(5 * mb); err != nil {
-				return problem.DefaultUnexpected
+		for i := -1; i < len(f.aliases); i++ {
+			name := f.name
+			if i >= 0 {
+				name = f.aliases[i]
 			}
-			e := elem.Field(i)
-			file, _, err := d.r.FormFile(fileTag.Name)
-			if err != nil && fileTag.HasOption("required") {
-				d.addParamsError(problem.ParamError{
-					Field:  fileTag.Name,
-					Reason: "this file is required",
-				})
-				continue
-			} else if err != nil && !fileTag.HasOption("required") {
-				continue
+			if isKeyed(f.source) {
+				name = joinKey(d.requestKey(f.source, prefix), name)
 			}
-
-			b, err := ioutil.ReadAll(file)
-			if err != nil {
-				return problem.DefaultUnexpected
-			}
-			if err = e.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(b); err != nil {
-				d.addParamsError(problem.ParamError{
-					Field:  fileTag.Name,
-					Reason: fmt.Sprintf("an error occured via UnmarshalBinary: %v", err),
-				})
+			if d.given(f, name) {
+				return true
 			}
 		}
 	}
-	return d.Input
+	return false
+}
+
+// decodeNested decodes the nested struct e. A pointer to a struct is only
+// allocated, and its fields checked, when one of its keys is given.
+func (d *Decoder) decodeNested(e reflect.Value, f *field, key string) error {
+	p := f.nested
+	if e.Kind() != reflect.Ptr {
+		return d.decodeStruct(e, p, key)
+	}
+	if !d.hasPrefix(f.source, key) {
+		return nil
+	}
+	v := reflect.New(e.Type().Elem())
+	if err := d.decodeStruct(v.Elem(), p, key); err != nil {
+		return err
+	}
+	e.Set(v)
+	return nil
 }
 
-func (d *Decoder) extractForm(key string, required bool, e reflect.Value) {
-	val := d.r.FormValue(key)
+func (d *Decoder) extractForm(key string, f *field, e reflect.Value) error {
+	vals, present := d.lookup(f.source, key)
+	if present {
+		d.markProvided(key)
+	}
+	var val string
+	if len(vals) > 0 {
+		val = vals[0]
+	}
 	// If bool strict mode is deactivated having ?<key> will be evaluated as true
-	if val == "" && required && e.Type().Name() == "bool" && !d.BoolStrictMode {
-		e.SetBool(true)
-		return
+	if val == "" && f.required && indirectType(e.Type()).Kind() == reflect.Bool && !d.BoolStrictMode {
+		d.convert(e, "true", f)
+		return nil
 	}
-	if val == "" && required {
-		d.addParamsError(problem.ParamError{
-			Field:  key,
-			Reason: "parameter is required",
-		})
-		return
-	} else if val == "" && !required {
-		return
+	// An explicit empty value is accepted with allowempty, pointers are
+	// then allocated to distinguish it from a missing key.
+	if val == "" && present && f.allowEmpty {
+		if e.Kind() == reflect.Ptr {
+			e.Set(reflect.New(e.Type().Elem()))
+		} else {
+			e.Set(reflect.Zero(e.Type()))
+		}
+		return nil
 	}
-	d.setFromType(e, key, val)
+	if val == "" && f.hasDef {
+		return d.setDefault(e, key, f.def, f)
+	}
+	if val == "" && f.required {
+		d.addParamsReason(key, f.source, errRequired)
+		return nil
+	} else if val == "" && !f.required {
+		return nil
+	}
+	if ok, err := d.setFromType(e, key, val, f); !ok {
+		return err
+	}
+	return d.checkRules(key, f, e)
+}
+
+// checkRules checks the decoded value e against its validate rules.
+func (d *Decoder) checkRules(key string, f *field, e reflect.Value) error {
+	errs, err := f.rules.Validate(key, e)
+	if err != nil {
+		return err
+	}
+	for _, paramErr := range errs {
+		paramErr.In = f.source
+		d.addParamsError(paramErr)
+	}
+	return nil
+}
+
+// setDefault sets the default value of a missing parameter. The default
+// being part of the tag, a conversion failure is a TagError.
+func (d *Decoder) setDefault(e reflect.Value, key, def string, f *field) error {
+	if err := d.convert(e, def, f); err != nil {
+		return &TagError{Field: key, Option: "default", Err: err}
+	}
+	return nil
+}
+
+// markProvided records key, and the keys of the structs it is nested into,
+// as given in the request.
+func (d *Decoder) markProvided(key string) {
+	if d.provided == nil {
+		d.provided = make(map[string]bool)
+	}
+	for {
+		d.provided[key] = true
+		i := strings.LastIndex(key, ".")
+		if i < 0 {
+			return
+		}
+		key = key[:i]
+	}
+}
+
+// Provided reports whether the parameter key was given in the request once
+// decoded, even with an empty value. Nested keys use the dotted notation,
+// e.g address.city, and a nested struct is provided if one of its keys is.
+func (d *Decoder) Provided(key string) bool {
+	return d.provided[key]
+}
+
+// ProvidedKeys returns the sorted keys of the decoded parameters which were
+// given in the request, see Provided.
+func (d *Decoder) ProvidedKeys() []string {
+	keys := make([]string, 0, len(d.provided))
+	for key := range d.provided {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hasPathError reports whether a path parameter is invalid.
+func (d *Decoder) hasPathError() bool {
+	if d.Input == nil {
+		return false
+	}
+	for _, paramErr := range d.Input.InvalidParams {
+		if paramErr.In == SourcePath {
+			return true
+		}
+	}
+	return false
 }
 
 func (d *Decoder) addParamsError(e problem.ParamError) {
@@ -109,67 +392,83 @@ func (d *Decoder) addParamsError(e problem.ParamError) {
 	d.Input.InvalidParams = append(d.Input.InvalidParams, e)
 }
 
+// addParamsReason adds the error of the parameter key given in in, with err
+// as reason, see problem.NewParamError.
+func (d *Decoder) addParamsReason(key, in string, err error) {
+	paramErr := problem.NewParamError(key, err)
+	paramErr.In = in
+	d.addParamsError(paramErr)
+}
+
 func (d *Decoder) initInputProblem() {
-	prob := problem.DefaultInput
-	prob.Title = "Your form parameters could not be decoded"
-	d.Input = &prob
-}
-
-func (d *Decoder) setFromType(e reflect.Value, key, val string) {
-	switch e.Type().Name() {
-	case "string":
-		e.SetString(val)
-		break
-	case "int64":
-		fallthrough
-	case "int":
-		v, err := strconv.Atoi(val)
-		if err != nil {
-			d.addParamsError(problem.ParamError{
-				Field:  key,
-				Reason: "syntax error: unable to convert to integer",
-			})
-			break
-		}
-		e.SetInt(int64(v))
-		break
-	case "float32":
-		fallthrough
-	case "float64":
-		v, err := strconv.ParseFloat(val, 32)
-		if err != nil {
-			d.addParamsError(problem.ParamError{
-				Field:  key,
-				Reason: "syntax error: unable to convert to float",
-			})
-			break
-		}
-		e.SetFloat(float64(v))
-		break
-	case "bool":
-		v, err := strconv.ParseBool(val)
-		if err != nil {
-			d.addParamsError(problem.ParamError{
-				Field:  key,
-				Reason: "syntax error: unable to convert to bool",
-			})
-			break
-		}
-		e.SetBool(v)
-		break
-	default:
-		var err error
-		targetType := reflect.PtrTo(e.Type())
-		if targetType.Implements(TextUnmarshalerType) {
-			err = e.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
-		} else if targetType.Implements(StringSetterType) {
-			err = e.Addr().Interface().(StringSetter).Set(val)
-		}
-		if err != nil {
-			d.addParamsError(problem.ParamError{
-				Field:  key,
-				Reason: fmt.Sprintf("an error occured via UnmarshalText: %v", err),
-			})
+	// The payload is copied, DefaultInput being shared
+	payload := *problem.DefaultInput.Payload
+	payload.TitleID = "form.input"
+	payload.Title = problem.Format(problem.DefaultLanguage, payload.TitleID, nil)
+	d.Input = &problem.Input{Payload: &payload}
+}
+
+// setFromType converts val to the type of e and stores it, it reports whether
+// it succeeded, errors being added to the decoder input problem. Programming
+// errors, such as a ConverterError, are returned.
+func (d *Decoder) setFromType(e reflect.Value, key, val string, f *field) (bool, error) {
+	if err := d.convert(e, val, f); err != nil {
+		if convErr, ok := err.(*ConverterError); ok {
+			return false, convErr
 		}
+		d.addParamsReason(key, f.source, err)
+		return false, nil
+	}
+	return true, nil
+}
+
+// isNested reports whether t is a struct whose fields should be decoded
+// from nested keys instead of being unmarshalled from a single value.
+func (c *compiler) isNested(t reflect.Type) bool {
+	t = indirectType(t)
+	return t.Kind() == reflect.Struct && !isUnmarshaler(t) && t != timeType && !c.config.hasConverter(t) && !isValuesUnmarshaler(t) && t != paginationType
+}
+
+// isSlice reports whether t is a slice whose elements are decoded one by one,
+// slices such as IntList unmarshalling themselves are excluded.
+func (c *compiler) isSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && !isUnmarshaler(t) && !c.config.hasConverter(t)
+}
+
+// isUnmarshaler reports whether values of type t unmarshal themselves
+// from a string.
+func isUnmarshaler(t reflect.Type) bool {
+	targetType := reflect.PtrTo(t)
+	return targetType.Implements(TextUnmarshalerType) || targetType.Implements(StringSetterType)
+}
+
+// indirectType returns the type pointed to by t if t is a pointer, t otherwise.
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// joinKey returns the dotted key of name nested into prefix, prefix itself
+// if name is empty.
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	if name == "" {
+		return prefix
+	}
+	return prefix + "." + name
+}
+
+var bracketReplacer = strings.NewReplacer("[]", "", "[", ".", "]", "")
+
+// normalizeKey turns the bracket notation of a key into the dotted one,
+// e.g address[city] becomes address.city and ids[] becomes ids.
+func normalizeKey(key string) string {
+	if !strings.ContainsAny(key, "[]") {
+		return key
 	}
+	return bracketReplacer.Replace(key)
 }
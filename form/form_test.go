@@ -1,12 +1,25 @@
+// ServeMux wildcards are disabled by the go version of go.mod before Go 1.22,
+// they are enabled for TestFormDecoderPath.
+//go:debug httpmuxgo121=0
+
 package form_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sganon/go-request/form"
 	"github.com/sganon/go-request/problem"
+	"github.com/sganon/go-request/validate"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -146,3 +159,1074 @@ func TestFormDecoder(t *testing.T) {
 		assert.Equal(t, te.Output, te.Input, "input should have been correctly decoded", i)
 	}
 }
+
+type nestedInput struct {
+	Name    string  `form:"name"`
+	Address address `form:"address"`
+}
+
+type address struct {
+	City    string `form:"city,required"`
+	Zip     int    `form:"zip"`
+	Country struct {
+		Code string `form:"code"`
+	} `form:"country"`
+}
+
+func TestFormDecoderNested(t *testing.T) {
+	nestedTests := []struct {
+		Query  string
+		Output nestedInput
+		Errors []problem.ParamError
+	}{
+		{
+			Query: "?name=foo&address.city=Paris&address[zip]=75001&address[country][code]=FR",
+			Output: nestedInput{
+				Name:    "foo",
+				Address: address{City: "Paris", Zip: 75001},
+			},
+		},
+		{
+			Query:  "?name=foo&address.zip=abc",
+			Output: nestedInput{Name: "foo"},
+			Errors: []problem.ParamError{
+				{Field: "address.city", Reason: "parameter is required", In: "form", Code: "required", MessageID: "form.required"},
+				{Field: "address.zip", Reason: "syntax error: unable to convert to integer", In: "form", Code: "type_mismatch", MessageID: "form.int_syntax", Params: map[string]interface{}{"expected_type": "integer"}},
+			},
+		},
+	}
+	nestedTests[0].Output.Address.Country.Code = "FR"
+
+	for i, te := range nestedTests {
+		req, err := http.NewRequest("GET", "http://localhost:80/"+te.Query, nil)
+		assert.Nil(t, err, "request should have been created", i)
+		var output nestedInput
+		decoder := form.NewDecoder(req)
+		err = decoder.Decode(&output)
+		if len(te.Errors) > 0 {
+			assert.NotNil(t, decoder.Input, "decode should have returned an error", i)
+			assert.Equal(t, te.Errors, decoder.Input.InvalidParams, i)
+		} else {
+			assert.Nil(t, decoder.Input, "decode should not have returned an error", i)
+		}
+		assert.Equal(t, te.Output, output, "input should have been correctly decoded", i)
+	}
+}
+
+type Pagination struct {
+	Limit  int `form:"limit,default=20"`
+	Offset int `form:"offset"`
+}
+
+type Auth struct {
+	Token string `header:"x-token,required"`
+}
+
+type Period struct {
+	From form.Date `form:"from"`
+	To   form.Date `form:"to"`
+}
+
+type sorting struct {
+	Sort string `form:"sort"`
+}
+
+func TestFormDecoderEmbedded(t *testing.T) {
+	type input struct {
+		Pagination
+		*Auth
+		Period `form:",prefix=period_"`
+		sorting
+		Name string `form:"name"`
+	}
+	req := httptest.NewRequest("GET", "/?name=foo&offset=10&period_from=2020-01-02&sort=name", nil)
+	var output input
+	decoder := form.NewDecoder(req)
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, Pagination{Limit: 20, Offset: 10}, output.Pagination)
+	assert.Nil(t, output.Auth, "an embedded pointer should only be allocated when one of its fields is given")
+	assert.Equal(t, form.Date{Year: 2020, Month: 1, Day: 2}, output.From)
+	assert.Equal(t, "name", output.Sort)
+	assert.Equal(t, "foo", output.Name)
+
+	req.Header.Set("X-Token", "secret")
+	output = input{}
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	if assert.NotNil(t, output.Auth) {
+		assert.Equal(t, "secret", output.Token)
+	}
+
+	type shadowing struct {
+		Pagination
+		Limit string `form:"limit"`
+	}
+	req = httptest.NewRequest("GET", "/?limit=all", nil)
+	var shadowed shadowing
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&shadowed)
+	assert.Nil(t, decoder.Input, "the least nested field should win")
+	assert.Equal(t, "all", shadowed.Limit)
+	assert.Equal(t, 0, shadowed.Pagination.Limit)
+
+	type page struct {
+		Limit int `form:"limit"`
+	}
+	err := form.Compile(struct {
+		Pagination
+		page
+	}{})
+	if assert.IsType(t, &form.TagError{}, err, "keys defined by several embedded structs should be ambiguous") {
+		assert.Equal(t, "limit", err.(*form.TagError).Field)
+	}
+	assert.NoError(t, form.Compile(struct {
+		Pagination
+		page `form:",prefix=page."`
+	}{}))
+	assert.Error(t, form.Compile(struct {
+		Pagination `form:",required"`
+	}{}))
+
+	values, err := form.NewEncoder().Encode(input{Pagination: Pagination{Limit: 10}, Period: Period{From: form.Date{Year: 2020, Month: 1, Day: 2}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "limit=10&name=&offset=0&period_from=2020-01-02&period_to=&sort=", values.Encode())
+}
+
+type sliceInput struct {
+	IDs     []int             `form:"ids,unique,maxitems=3"`
+	Names   []string          `form:"names,sep=|"`
+	Ratios  []float64         `form:"ratios,minitems=2"`
+	Dates   []time.Time       `form:"dates"`
+	Authors []form.StringList `form:"authors"`
+	Ranks   []*int64          `form:"ranks,unique"`
+}
+
+func TestFormDecoderSlice(t *testing.T) {
+	date := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	sliceTests := []struct {
+		Query  string
+		Output sliceInput
+		Errors []problem.ParamError
+	}{
+		{
+			Query:  "?ids=1&ids=2&names=foo,bar|baz&ratios[]=0.5&ratios[]=1.5&dates=2019-01-02T03:04:05Z",
+			Output: sliceInput{IDs: []int{1, 2}, Names: []string{"foo,bar", "baz"}, Ratios: []float64{0.5, 1.5}, Dates: []time.Time{date}},
+		},
+		{
+			Query:  "?ids=1,2,3&authors=a,b&authors=c",
+			Output: sliceInput{IDs: []int{1, 2, 3}, Authors: []form.StringList{{"a"}, {"b"}, {"c"}}},
+		},
+		{
+			Query: "?ids=1,a,3,b",
+			Errors: []problem.ParamError{
+				{Field: "ids[1]", Reason: "syntax error: unable to convert to integer", In: "form", Code: "type_mismatch", MessageID: "form.int_syntax", Params: map[string]interface{}{"expected_type": "integer"}},
+				{Field: "ids[3]", Reason: "syntax error: unable to convert to integer", In: "form", Code: "type_mismatch", MessageID: "form.int_syntax", Params: map[string]interface{}{"expected_type": "integer"}},
+			},
+		},
+		{
+			Query: "?ids=1,2,01&ratios=1",
+			Errors: []problem.ParamError{
+				{Field: "ids[2]", Reason: "duplicate value \"01\", items must be unique", In: "form", Code: "duplicate", MessageID: "form.duplicate", Params: map[string]interface{}{"value": "01"}},
+				{Field: "ratios", Reason: "expected at least 2 items, got 1", In: "form", Code: "length", MessageID: "form.min_items", Params: map[string]interface{}{"min": 2, "count": 1}},
+			},
+		},
+		{
+			Query:  "?ranks=1,2",
+			Output: sliceInput{Ranks: []*int64{int64Ptr(1), int64Ptr(2)}},
+		},
+		{
+			Query: "?ranks=1,2,01",
+			Errors: []problem.ParamError{
+				{Field: "ranks[2]", Reason: "duplicate value \"01\", items must be unique", In: "form", Code: "duplicate", MessageID: "form.duplicate", Params: map[string]interface{}{"value": "01"}},
+			},
+		},
+		{
+			Query: "?ids=1,2,3,4",
+			Errors: []problem.ParamError{
+				{Field: "ids", Reason: "expected at most 3 items, got 4", In: "form", Code: "length", MessageID: "form.max_items", Params: map[string]interface{}{"max": 3, "count": 4}},
+			},
+		},
+	}
+
+	for i, te := range sliceTests {
+		req, err := http.NewRequest("GET", "http://localhost:80/"+te.Query, nil)
+		assert.Nil(t, err, "request should have been created", i)
+		var output sliceInput
+		decoder := form.NewDecoder(req)
+		decoder.Decode(&output)
+		if len(te.Errors) > 0 {
+			assert.NotNil(t, decoder.Input, "decode should have returned an error", i)
+			assert.Equal(t, te.Errors, decoder.Input.InvalidParams, i)
+		} else {
+			assert.Nil(t, decoder.Input, "decode should not have returned an error", i)
+		}
+		assert.Equal(t, te.Output, output, "input should have been correctly decoded", i)
+	}
+}
+
+type label string
+
+func TestFormDecoderMap(t *testing.T) {
+	type input struct {
+		Meta   map[string]string       `form:"meta"`
+		Sizes  map[string]int          `form:"sizes,keys=small,medium,large,maxkeys=2"`
+		Days   map[label]form.Date     `form:"days" validate:"maxlen=1"`
+		Labels map[string]string       `query:"labels,required" validate:"maxlen=10"`
+		Scores map[string]*float64     `form:"scores" validate:"max=10"`
+		Lists  map[string]form.IntList `form:"lists"`
+	}
+	req, err := http.NewRequest("GET", "http://localhost:80/?meta[color]=red&meta.size=9&sizes[small]=1&days[start]=2020-01-02&labels[env]=prod&labels[team]=&scores[a]=1.5&lists[a]=1,2", nil)
+	assert.NoError(t, err)
+	var output input
+	decoder := form.NewDecoder(req)
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, map[string]string{"color": "red", "size": "9"}, output.Meta)
+	assert.Equal(t, map[string]int{"small": 1}, output.Sizes)
+	assert.Equal(t, map[label]form.Date{"start": {Year: 2020, Month: 1, Day: 2}}, output.Days)
+	assert.Equal(t, map[string]string{"env": "prod", "team": ""}, output.Labels)
+	if assert.Contains(t, output.Scores, "a") {
+		assert.Equal(t, 1.5, *output.Scores["a"])
+	}
+	assert.Equal(t, map[string]form.IntList{"a": {1, 2}}, output.Lists)
+	assert.True(t, decoder.Provided("meta"))
+
+	req, err = http.NewRequest("GET", "http://localhost:80/?sizes[tiny]=1&sizes[small]=x&days[a]=2020-01-02&days[b]=2020-01-03&scores[a]=11", nil)
+	assert.NoError(t, err)
+	output = input{}
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "sizes[small]", Reason: "syntax error: unable to convert to integer", In: "form", Code: "type_mismatch", MessageID: "form.int_syntax", Params: map[string]interface{}{"expected_type": "integer"}},
+			{Field: "sizes[tiny]", Reason: `key "tiny" is not allowed, expected one of: small, medium, large`, In: "form", Code: "invalid_value", MessageID: "form.key", Params: map[string]interface{}{"key": "tiny", "expected": []string{"small", "medium", "large"}}},
+			{Field: "days", Reason: "length must be at most 1", In: "form", Code: "length", MessageID: "validate.maxlen", Params: map[string]interface{}{"max": 1}},
+			{Field: "labels", Reason: "parameter is required", In: "query", Code: "required", MessageID: "form.required"},
+			{Field: "scores[a]", Reason: "must be less than or equal to 10", In: "form", Code: "out_of_range", MessageID: "validate.max", Params: map[string]interface{}{"max": float64(10)}},
+		}, decoder.Input.InvalidParams)
+	}
+	assert.Nil(t, output.Sizes)
+
+	req, err = http.NewRequest("GET", "http://localhost:80/?sizes[small]=1&sizes[medium]=2&sizes[large]=3&labels[a]=b", nil)
+	assert.NoError(t, err)
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "sizes", Reason: "expected at most 2 keys, got 3", In: "form", Code: "length", MessageID: "form.max_keys", Params: map[string]interface{}{"max": 2, "count": 3}},
+		}, decoder.Input.InvalidParams)
+	}
+
+	err = form.Compile(struct {
+		Meta map[string]string `header:"meta"`
+	}{})
+	assert.IsType(t, &form.TagError{}, err, "maps should be decoded from keyed sources")
+	err = form.Compile(struct {
+		Meta map[int]string `form:"meta"`
+	}{})
+	assert.IsType(t, &form.TagError{}, err, "map keys should be strings")
+}
+
+type status string
+
+type kindInput struct {
+	Status  status  `form:"status"`
+	Small   int8    `form:"small"`
+	Port    uint16  `form:"port"`
+	ID      uint64  `form:"id"`
+	Ratio   float64 `form:"ratio"`
+	Enabled bool    `form:"enabled"`
+}
+
+func TestFormDecoderKinds(t *testing.T) {
+	kindTests := []struct {
+		Query      string
+		BoolValues map[string]bool
+		Output     kindInput
+		Errors     []problem.ParamError
+	}{
+		{
+			Query:  "?status=active&small=-128&port=8080&id=18446744073709551615&ratio=0.1234567890123&enabled=true",
+			Output: kindInput{Status: "active", Small: -128, Port: 8080, ID: 18446744073709551615, Ratio: 0.1234567890123, Enabled: true},
+		},
+		{
+			Query: "?small=128&port=65536&id=-1&ratio=1e400",
+			Errors: []problem.ParamError{
+				{Field: "small", Reason: "out of range: value must be between -128 and 127", In: "form", Code: "out_of_range", MessageID: "form.int_range", Params: map[string]interface{}{"min": int64(-128), "max": int64(127)}},
+				{Field: "port", Reason: "out of range: value must be between 0 and 65535", In: "form", Code: "out_of_range", MessageID: "form.int_range", Params: map[string]interface{}{"min": uint64(0), "max": uint64(65535)}},
+				{Field: "id", Reason: "syntax error: unable to convert to unsigned integer", In: "form", Code: "type_mismatch", MessageID: "form.uint_syntax", Params: map[string]interface{}{"expected_type": "unsigned_integer"}},
+				{Field: "ratio", Reason: "out of range: value does not fit in a 64-bit float", In: "form", Code: "out_of_range", MessageID: "form.float_range", Params: map[string]interface{}{"bits": 64}},
+			},
+		},
+		{
+			Query: "?ratio=NaN",
+			Errors: []problem.ParamError{
+				{Field: "ratio", Reason: "syntax error: unable to convert to float", In: "form", Code: "type_mismatch", MessageID: "form.float_syntax", Params: map[string]interface{}{"expected_type": "float"}},
+			},
+		},
+		{
+			Query: "?ratio=-Inf",
+			Errors: []problem.ParamError{
+				{Field: "ratio", Reason: "syntax error: unable to convert to float", In: "form", Code: "type_mismatch", MessageID: "form.float_syntax", Params: map[string]interface{}{"expected_type": "float"}},
+			},
+		},
+		{
+			Query:      "?enabled=Yes",
+			BoolValues: form.ExtendedBoolValues,
+			Output:     kindInput{Enabled: true},
+		},
+		{
+			Query: "?enabled=yes",
+			Errors: []problem.ParamError{
+				{Field: "enabled", Reason: "syntax error: unable to convert to bool", In: "form", Code: "type_mismatch", MessageID: "form.bool_syntax", Params: map[string]interface{}{"expected_type": "boolean"}},
+			},
+		},
+	}
+
+	for i, te := range kindTests {
+		req, err := http.NewRequest("GET", "http://localhost:80/"+te.Query, nil)
+		assert.Nil(t, err, "request should have been created", i)
+		var output kindInput
+		decoder := form.NewDecoder(req)
+		decoder.BoolValues = te.BoolValues
+		decoder.Decode(&output)
+		if len(te.Errors) > 0 {
+			assert.NotNil(t, decoder.Input, "decode should have returned an error", i)
+			assert.Equal(t, te.Errors, decoder.Input.InvalidParams, i)
+		} else {
+			assert.Nil(t, decoder.Input, "decode should not have returned an error", i)
+		}
+		assert.Equal(t, te.Output, output, "input should have been correctly decoded", i)
+	}
+}
+
+type pointerInput struct {
+	Name    *string  `form:"name,allowempty"`
+	Nick    *string  `form:"nick"`
+	Count   *int     `form:"count"`
+	Enabled *bool    `form:"enabled"`
+	Status  *status  `form:"status"`
+	Label   string   `form:"label,required,allowempty"`
+	Address *address `form:"address"`
+}
+
+func TestFormDecoderPointer(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://localhost:80/?name=&nick=&count=0&status=active&label=", nil)
+	assert.NoError(t, err)
+	var output pointerInput
+	decoder := form.NewDecoder(req)
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	if assert.NotNil(t, output.Name) {
+		assert.Equal(t, "", *output.Name)
+	}
+	assert.Nil(t, output.Nick)
+	if assert.NotNil(t, output.Count) {
+		assert.Equal(t, 0, *output.Count)
+	}
+	assert.Nil(t, output.Enabled)
+	if assert.NotNil(t, output.Status) {
+		assert.Equal(t, status("active"), *output.Status)
+	}
+	assert.Nil(t, output.Address)
+	assert.True(t, decoder.Provided("count"))
+	assert.False(t, decoder.Provided("enabled"))
+	assert.Equal(t, []string{"count", "label", "name", "nick", "status"}, decoder.ProvidedKeys())
+
+	req, err = http.NewRequest("GET", "http://localhost:80/?label=foo&address.zip=75001", nil)
+	assert.NoError(t, err)
+	output = pointerInput{}
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{{Field: "address.city", Reason: "parameter is required", In: "form", Code: "required", MessageID: "form.required"}}, decoder.Input.InvalidParams)
+	}
+	if assert.NotNil(t, output.Address) {
+		assert.Equal(t, 75001, output.Address.Zip)
+	}
+	assert.True(t, decoder.Provided("address"))
+}
+
+type timeInput struct {
+	From     time.Time      `form:"from"`
+	Until    time.Time      `form:"until,layout=2006-01-02 15:04,tz=Europe/Paris"`
+	Since    time.Time      `form:"since,layout=unix"`
+	SinceMs  time.Time      `form:"since_ms,layout=unixmilli"`
+	Local    time.Time      `form:"local,layout=2006-01-02 15:04"`
+	Timeout  time.Duration  `form:"timeout"`
+	Day      form.Date      `form:"day"`
+	Opening  form.TimeOfDay `form:"opening"`
+	Deadline *time.Time     `form:"deadline,layout=RFC1123"`
+}
+
+func TestFormDecoderTime(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest("GET", "http://localhost:80/?from=2019-01-02T03:04:05Z&until=2019-01-02+03:04"+
+		"&since=1546398245&since_ms=1546398245123&local=2019-01-02+03:04&timeout=1h30m&day=2019-02-28&opening=08:30"+
+		"&deadline=Wed,+02+Jan+2019+03:04:05+UTC", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Time-Zone", "Asia/Tokyo")
+	var output timeInput
+	decoder := form.NewDecoder(req)
+	decoder.LocationHeader = "Time-Zone"
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.True(t, time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC).Equal(output.From))
+	assert.Equal(t, time.Date(2019, 1, 2, 3, 4, 0, 0, paris), output.Until)
+	assert.True(t, time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC).Equal(output.Since))
+	assert.True(t, time.Date(2019, 1, 2, 3, 4, 5, 123e6, time.UTC).Equal(output.SinceMs))
+	assert.Equal(t, time.Date(2019, 1, 2, 3, 4, 0, 0, tokyo), output.Local)
+	assert.Equal(t, 90*time.Minute, output.Timeout)
+	assert.Equal(t, form.Date{Year: 2019, Month: time.February, Day: 28}, output.Day)
+	assert.Equal(t, form.TimeOfDay{Hour: 8, Minute: 30}, output.Opening)
+	if assert.NotNil(t, output.Deadline) {
+		assert.True(t, time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC).Equal(*output.Deadline))
+	}
+
+	req, err = http.NewRequest("GET", "http://localhost:80/?from=2019-01-02&since=yesterday&timeout=1y&day=2019-02-30&opening=25:00", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Time-Zone", "Mars/Olympus")
+	output = timeInput{}
+	decoder = form.NewDecoder(req)
+	decoder.LocationHeader = "Time-Zone"
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "Time-Zone", Reason: "unknown time zone \"Mars/Olympus\"", In: "header", Code: "invalid_value", MessageID: "form.time_zone", Params: map[string]interface{}{"zone": "Mars/Olympus"}},
+			{Field: "from", Reason: "syntax error: expected a time in format 2006-01-02T15:04:05Z07:00", In: "form", Code: "invalid_format", MessageID: "form.time_syntax", Params: map[string]interface{}{"layout": "2006-01-02T15:04:05Z07:00"}},
+			{Field: "since", Reason: "syntax error: expected a unix timestamp in seconds", In: "form", Code: "invalid_format", MessageID: "form.unix_syntax"},
+			{Field: "timeout", Reason: "syntax error: expected a duration such as 1h30m", In: "form", Code: "invalid_format", MessageID: "form.duration_syntax"},
+			{Field: "day", Reason: "syntax error: expected a date in format 2006-01-02", In: "form", Code: "invalid_format", MessageID: "form.date_syntax"},
+			{Field: "opening", Reason: "syntax error: expected a time of day in format 15:04 or 15:04:05", In: "form", Code: "invalid_format", MessageID: "form.clock_syntax"},
+		}, decoder.Input.InvalidParams)
+	}
+}
+
+func TestFormDecoderTagError(t *testing.T) {
+	var output struct {
+		At time.Time `form:"at,tz=Mars/Olympus"`
+	}
+	req, err := http.NewRequest("GET", "http://localhost:80/?at=2019-01-02T03:04:05Z", nil)
+	assert.NoError(t, err)
+	err = form.NewDecoder(req).Decode(&output)
+	if assert.IsType(t, &form.TagError{}, err) {
+		assert.Equal(t, "at", err.(*form.TagError).Field)
+	}
+}
+
+func TestTimeOfDayString(t *testing.T) {
+	assert.Equal(t, "08:30:00", form.TimeOfDay{Hour: 8, Minute: 30}.String())
+	assert.Equal(t, "08:30:00.25", form.TimeOfDay{Hour: 8, Minute: 30, Nanosecond: 25e7}.String())
+	assert.Equal(t, "2019-02-03", form.Date{Year: 2019, Month: 2, Day: 3}.String())
+}
+
+type defaultInput struct {
+	Limit  int           `form:"limit,default=20"`
+	Offset *int          `form:"offset,default=0"`
+	Sort   []string      `form:"sort,default=name,id"`
+	Every  time.Duration `form:"every,default=1m"`
+}
+
+func TestFormDecoderDefault(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://localhost:80/?limit=50", nil)
+	assert.NoError(t, err)
+	var output defaultInput
+	err = form.NewDecoder(req).Decode(&output)
+	assert.Nil(t, err.(*problem.Input))
+	assert.Equal(t, 50, output.Limit)
+	if assert.NotNil(t, output.Offset) {
+		assert.Equal(t, 0, *output.Offset)
+	}
+	assert.Equal(t, []string{"name", "id"}, output.Sort)
+	assert.Equal(t, time.Minute, output.Every)
+
+	var invalid struct {
+		Limit int `form:"limit,default=twenty"`
+	}
+	err = form.NewDecoder(req).Decode(&invalid)
+	if assert.IsType(t, &form.TagError{}, err) {
+		assert.Equal(t, "limit", err.(*form.TagError).Field)
+		assert.Equal(t, "default", err.(*form.TagError).Option)
+	}
+}
+
+type rulesInput struct {
+	Limit  int      `form:"limit" validate:"min=1,max=100"`
+	Status string   `form:"status" validate:"oneof=active pending"`
+	Email  string   `form:"email" validate:"required,email"`
+	IDs    []string `form:"ids" validate:"maxlen=2,uuid"`
+}
+
+func TestFormDecoderRules(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://localhost:80/?limit=0&status=active&ids=6ba7b810-9dad-11d1-80b4-00c04fd430c8,foo", nil)
+	assert.NoError(t, err)
+	var output rulesInput
+	decoder := form.NewDecoder(req)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "limit", Reason: "must be greater than or equal to 1", In: "form", Code: "out_of_range", MessageID: "validate.min", Params: map[string]interface{}{"min": int64(1)}},
+			{Field: "email", Reason: "parameter is required", In: "form", Code: "required", MessageID: "form.required"},
+			{Field: "ids[1]", Reason: "must be a valid UUID", In: "form", Code: "invalid_format", MessageID: "validate.uuid", Params: map[string]interface{}{"format": "uuid"}},
+		}, decoder.Input.InvalidParams)
+	}
+
+	var counts struct {
+		Count *int `form:"count" validate:"required"`
+		N     int  `form:"n" validate:"required"`
+	}
+	req, err = http.NewRequest("GET", "http://localhost:80/?count=0&n=0", nil)
+	assert.NoError(t, err)
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&counts)
+	assert.Nil(t, decoder.Input, "given zero values should satisfy required")
+	if assert.NotNil(t, counts.Count) {
+		assert.Equal(t, 0, *counts.Count)
+	}
+
+	req, err = http.NewRequest("GET", "http://localhost:80/", nil)
+	assert.NoError(t, err)
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&counts)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "count", Reason: "parameter is required", In: "form", Code: "required", MessageID: "form.required"},
+			{Field: "n", Reason: "parameter is required", In: "form", Code: "required", MessageID: "form.required"},
+		}, decoder.Input.InvalidParams)
+	}
+
+	var invalid struct {
+		Name string `form:"name" validate:"min=1"`
+	}
+	req, err = http.NewRequest("GET", "http://localhost:80/?name=foo", nil)
+	assert.NoError(t, err)
+	err = form.NewDecoder(req).Decode(&invalid)
+	if assert.IsType(t, &form.TagError{}, err) {
+		assert.IsType(t, &validate.InvalidRuleError{}, err.(*form.TagError).Err)
+	}
+}
+
+type benchInput struct {
+	Query   string    `form:"q"`
+	Limit   int       `form:"limit,default=20" validate:"min=1,max=100"`
+	Offset  int       `form:"offset"`
+	IDs     []int     `form:"ids,unique"`
+	Since   time.Time `form:"since"`
+	Enabled *bool     `form:"enabled"`
+	Address address   `form:"address"`
+}
+
+func BenchmarkDecode(b *testing.B) {
+	req, err := http.NewRequest("GET", "http://localhost:80/?q=foo&limit=50&ids=1,2,3&since=2019-01-02T03:04:05Z&enabled=true&address.city=Paris", nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	req.ParseForm()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var output benchInput
+		form.NewDecoder(req).Decode(&output)
+	}
+}
+
+type node struct {
+	Name  string `form:"name"`
+	Child *node  `form:"child"`
+}
+
+func TestCompile(t *testing.T) {
+	assert.NoError(t, form.Compile(input{}))
+	assert.NoError(t, form.Compile(&nestedInput{}))
+	assert.NoError(t, form.Compile(reflect.TypeOf(node{})))
+	assert.Error(t, form.Compile(42))
+
+	invalids := []interface{}{
+		struct {
+			Meta chan int `form:"meta"`
+		}{},
+		struct {
+			Limit int `form:"limit,unknown"`
+		}{},
+		struct {
+			Limit int `form:"limit,maxitems=two"`
+		}{},
+		struct {
+			Limit int `form:"limit" file:"limit"`
+		}{},
+		struct {
+			File string `file:"file"`
+		}{},
+		struct {
+			Limit int `form:"limit" validate:"unknown"`
+		}{},
+		struct {
+			Limit int `form:"limit" validate:"min=abc"`
+		}{},
+		struct {
+			Name string `form:"name" validate:"pattern=[a"`
+		}{},
+		struct {
+			Limit int `form:"limit" validate:"email"`
+		}{},
+		struct {
+			IDs []int `form:"ids" validate:"maxlen=2,uuid"`
+		}{},
+	}
+	for i, v := range invalids {
+		err := form.Compile(v)
+		assert.Error(t, err, i)
+		if i >= 5 && assert.IsType(t, &form.TagError{}, err, i) {
+			assert.Equal(t, "validate", err.(*form.TagError).Option, i)
+		}
+	}
+
+	err := form.Compile(struct {
+		Limit int `form:"limit,default=twenty"`
+	}{})
+	if assert.IsType(t, &form.TagError{}, err) {
+		assert.Equal(t, "limit", err.(*form.TagError).Field)
+	}
+
+	req, err := http.NewRequest("GET", "http://localhost:80/?name=root&child.name=leaf&child.child.name=leaf2", nil)
+	assert.NoError(t, err)
+	var output node
+	form.NewDecoder(req).Decode(&output)
+	if assert.NotNil(t, output.Child) && assert.NotNil(t, output.Child.Child) {
+		assert.Equal(t, "leaf2", output.Child.Child.Name)
+		assert.Nil(t, output.Child.Child.Child)
+	}
+}
+
+type sourceInput struct {
+	Merged   string   `form:"id"`
+	Query    string   `query:"id"`
+	PostForm string   `postform:"id,required"`
+	Token    string   `header:"x-token,required"`
+	Accept   []string `header:"Accept"`
+	Session  int      `cookie:"session"`
+	Filter   struct {
+		Name string `query:"name"`
+	} `query:"filter"`
+}
+
+func TestFormDecoderSources(t *testing.T) {
+	req, err := http.NewRequest("POST", "http://localhost:80/?id=query&filter[name]=foo", strings.NewReader("id=body"))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Token", "secret")
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "42"})
+	var output sourceInput
+	decoder := form.NewDecoder(req)
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, "body", output.Merged, "body values should take precedence in form, see http.Request.FormValue")
+	assert.Equal(t, "query", output.Query)
+	assert.Equal(t, "body", output.PostForm)
+	assert.Equal(t, "secret", output.Token)
+	assert.Equal(t, []string{"text/html", "application/json"}, output.Accept)
+	assert.Equal(t, 42, output.Session)
+	assert.Equal(t, "foo", output.Filter.Name)
+
+	req, err = http.NewRequest("GET", "http://localhost:80/?id=query", nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	output = sourceInput{}
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "id", Reason: "parameter is required", In: "postform", Code: "required", MessageID: "form.required"},
+			{Field: "x-token", Reason: "parameter is required", In: "header", Code: "required", MessageID: "form.required"},
+			{Field: "session", Reason: "syntax error: unable to convert to integer", In: "cookie", Code: "type_mismatch", MessageID: "form.int_syntax", Params: map[string]interface{}{"expected_type": "integer"}},
+		}, decoder.Input.InvalidParams)
+	}
+
+	err = form.Compile(struct {
+		ID string `query:"id" header:"id"`
+	}{})
+	assert.Error(t, err, "a field should have a single source")
+}
+
+func TestFormDecoderUnknownParams(t *testing.T) {
+	type input struct {
+		Limit  int    `query:"limit"`
+		Name   string `postform:"name"`
+		Filter struct {
+			Status string `form:"status"`
+		} `form:"filter"`
+	}
+	tests := []struct {
+		name  string
+		query string
+		body  string
+		want  []problem.ParamError
+	}{
+		{
+			name:  "declared",
+			query: "limit=10&filter[status]=open&trace_id=1&utm_source=mail",
+			body:  "name=foo",
+		},
+		{
+			name:  "suggestion",
+			query: "lmit=10&filter.stats=open",
+			want: []problem.ParamError{
+				{Field: "filter.stats", Reason: `unknown parameter, did you mean "filter.status"?`, In: "query", Code: "unknown_parameter", MessageID: "form.unknown_suggested", Params: map[string]interface{}{"suggestion": "filter.status"}},
+				{Field: "lmit", Reason: `unknown parameter, did you mean "limit"?`, In: "query", Code: "unknown_parameter", MessageID: "form.unknown_suggested", Params: map[string]interface{}{"suggestion": "limit"}},
+			},
+		},
+		{
+			name:  "unknown",
+			query: "sort=name&x=1",
+			want: []problem.ParamError{
+				{Field: "sort", Reason: "unknown parameter", In: "query", Code: "unknown_parameter", MessageID: "form.unknown"},
+				{Field: "x", Reason: "unknown parameter", In: "query", Code: "unknown_parameter", MessageID: "form.unknown"},
+			},
+		},
+		{
+			name:  "wrong source",
+			query: "name=foo",
+			body:  "limit=10",
+			want: []problem.ParamError{
+				{Field: "name", Reason: "unknown parameter", In: "query", Code: "unknown_parameter", MessageID: "form.unknown"},
+				{Field: "limit", Reason: "unknown parameter", In: "postform", Code: "unknown_parameter", MessageID: "form.unknown"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/?"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			var output input
+			decoder := form.NewDecoder(req, form.WithDisallowUnknownParams("trace_id", "utm_*"))
+			decoder.Decode(&output)
+			if tt.want == nil {
+				assert.Nil(t, decoder.Input)
+			} else if assert.NotNil(t, decoder.Input) {
+				assert.Equal(t, tt.want, decoder.Input.InvalidParams)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/?lmit=10", nil)
+	decoder := form.NewDecoder(req)
+	assert.Nil(t, decoder.Decode(&struct {
+		Limit int `query:"limit"`
+	}{}), "unknown parameters should be ignored by default")
+}
+
+// decimal stands for a third-party type which cannot implement TextUnmarshaler.
+type decimal struct {
+	units, cents int64
+}
+
+type objectID [4]byte
+
+func init() {
+	form.RegisterConverter(reflect.TypeOf(decimal{}), func(s string) (interface{}, error) {
+		var d decimal
+		if _, err := fmt.Sscanf(s, "%d.%d", &d.units, &d.cents); err != nil {
+			return nil, errors.New("invalid decimal")
+		}
+		return d, nil
+	})
+	form.RegisterConverter(reflect.TypeOf(objectID{}), func(s string) (interface{}, error) {
+		var id objectID
+		if len(s) != len(id) {
+			return nil, errors.New("invalid object id")
+		}
+		copy(id[:], s)
+		return id, nil
+	})
+}
+
+func TestFormDecoderConverter(t *testing.T) {
+	type input struct {
+		Price   decimal    `form:"price"`
+		Prices  []decimal  `form:"prices"`
+		Max     *decimal   `form:"max"`
+		ID      objectID   `form:"id"`
+		Created int        `form:"created"`
+		Tags    []string   `form:"tags"`
+		Ptr     *objectID  `form:"ptr"`
+		IDs     []objectID `form:"ids"`
+	}
+	req, err := http.NewRequest("GET", "http://localhost:80/?price=1.50&prices=1.1,2.2&max=9.99&id=abcd&created=yesterday&tags=a,b&ptr=efgh&ids=ijkl", nil)
+	assert.NoError(t, err)
+	var output input
+	decoder := form.NewDecoder(req, form.WithConverter(reflect.TypeOf(0), func(s string) (interface{}, error) {
+		if s == "yesterday" {
+			return -1, nil
+		}
+		return strconv.Atoi(s)
+	}))
+	decoder.RegisterConverter(reflect.TypeOf(""), func(s string) (interface{}, error) {
+		return strings.ToUpper(s), nil
+	})
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, decimal{1, 50}, output.Price)
+	assert.Equal(t, []decimal{{1, 1}, {2, 2}}, output.Prices)
+	assert.Equal(t, &decimal{9, 99}, output.Max)
+	assert.Equal(t, objectID{'a', 'b', 'c', 'd'}, output.ID)
+	assert.Equal(t, -1, output.Created, "decoder converters should take precedence over builtin conversions")
+	assert.Equal(t, []string{"A", "B"}, output.Tags)
+	assert.Equal(t, &objectID{'e', 'f', 'g', 'h'}, output.Ptr)
+	assert.Equal(t, []objectID{{'i', 'j', 'k', 'l'}}, output.IDs)
+
+	req, err = http.NewRequest("GET", "http://localhost:80/?price=abc&prices=1.1,x&id=a", nil)
+	assert.NoError(t, err)
+	output = input{}
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "price", Reason: "invalid decimal", In: "form"},
+			{Field: "prices[1]", Reason: "invalid decimal", In: "form"},
+			{Field: "id", Reason: "invalid object id", In: "form"},
+		}, decoder.Input.InvalidParams)
+	}
+
+	decoder = form.NewDecoder(req, form.WithConverter(reflect.TypeOf(decimal{}), func(s string) (interface{}, error) {
+		return s, nil
+	}))
+	err = decoder.Decode(&output)
+	assert.IsType(t, &form.ConverterError{}, err, "a converter returning another type is a programming error")
+}
+
+// generatedID stands for a third-party struct only registered on decoders.
+type generatedID struct {
+	value string
+}
+
+func TestFormDecoderStructConverter(t *testing.T) {
+	type input struct {
+		ID     generatedID  `form:"id,required"`
+		Parent *generatedID `form:"parent"`
+	}
+	withID := form.WithConverter(reflect.TypeOf(generatedID{}), func(s string) (interface{}, error) {
+		if !strings.HasPrefix(s, "id_") {
+			return nil, errors.New("invalid id")
+		}
+		return generatedID{value: s}, nil
+	})
+
+	var output input
+	decoder := form.NewDecoder(httptest.NewRequest("GET", "/?id=id_1&parent=id_2", nil), withID)
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, generatedID{value: "id_1"}, output.ID)
+	assert.Equal(t, &generatedID{value: "id_2"}, output.Parent)
+	assert.True(t, decoder.Provided("id"))
+
+	output = input{}
+	decoder = form.NewDecoder(httptest.NewRequest("GET", "/?id=abc", nil), withID)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{{Field: "id", Reason: "invalid id", In: "form"}}, decoder.Input.InvalidParams)
+	}
+
+	decoder = form.NewDecoder(httptest.NewRequest("GET", "/", nil), withID)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, "id", decoder.Input.InvalidParams[0].Field)
+		assert.Equal(t, "required", decoder.Input.InvalidParams[0].Code)
+	}
+}
+
+// arrayID stands for a generated ID type which is otherwise unsupported.
+type arrayID [4]byte
+
+func TestFormDecoderArrayConverter(t *testing.T) {
+	type input struct {
+		ID  arrayID   `form:"id,default=0000"`
+		IDs []arrayID `form:"ids"`
+	}
+	withID := form.WithConverter(reflect.TypeOf(arrayID{}), func(s string) (interface{}, error) {
+		var id arrayID
+		if len(s) != len(id) {
+			return nil, errors.New("invalid id")
+		}
+		copy(id[:], s)
+		return id, nil
+	})
+	err := form.Compile(input{})
+	if assert.IsType(t, &form.TagError{}, err, "arrays need a converter") {
+		assert.Equal(t, "id", err.(*form.TagError).Field)
+	}
+	assert.NoError(t, form.Compile(input{}, withID))
+
+	var output input
+	decoder := form.NewDecoder(httptest.NewRequest("GET", "/?ids=abcd,efgh", nil))
+	decoder.RegisterConverter(reflect.TypeOf(arrayID{}), func(s string) (interface{}, error) {
+		var id arrayID
+		copy(id[:], s)
+		return id, nil
+	})
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, input{ID: arrayID{'0', '0', '0', '0'}, IDs: []arrayID{{'a', 'b', 'c', 'd'}, {'e', 'f', 'g', 'h'}}}, output)
+
+	output = input{}
+	decoder = form.NewDecoder(httptest.NewRequest("GET", "/?id=abc", nil), withID)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{{Field: "id", Reason: "invalid id", In: "form"}}, decoder.Input.InvalidParams)
+	}
+}
+
+func TestFormDecoderAliases(t *testing.T) {
+	type input struct {
+		UserID  int `form:"user_id|userId,required"`
+		Address *struct {
+			ZipCode string `form:"zip_code|zipCode"`
+		} `form:"address|addr"`
+		Token string `header:"x-token|x-auth-token"`
+	}
+	tests := []struct {
+		query   string
+		userID  int
+		zipCode string
+		want    []problem.ParamError
+	}{
+		{query: "user_id=1&address.zip_code=75001", userID: 1, zipCode: "75001"},
+		{query: "userId=2&addr[zipCode]=75002", userID: 2, zipCode: "75002"},
+		{query: "user_id=3&userId=4&addr.zip_code=75003", userID: 3, zipCode: "75003"},
+		{
+			query: "userId=abc",
+			want:  []problem.ParamError{{Field: "user_id", Reason: "syntax error: unable to convert to integer", In: "form", Code: "type_mismatch", MessageID: "form.int_syntax", Params: map[string]interface{}{"expected_type": "integer"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/?"+tt.query, nil)
+			req.Header.Set("X-Auth-Token", "secret")
+			var output input
+			decoder := form.NewDecoder(req)
+			decoder.Decode(&output)
+			if tt.want != nil {
+				if assert.NotNil(t, decoder.Input) {
+					assert.Equal(t, tt.want, decoder.Input.InvalidParams)
+				}
+				return
+			}
+			assert.Nil(t, decoder.Input)
+			assert.Equal(t, tt.userID, output.UserID)
+			if assert.NotNil(t, output.Address) {
+				assert.Equal(t, tt.zipCode, output.Address.ZipCode)
+			}
+			assert.Equal(t, "secret", output.Token)
+			assert.True(t, decoder.Provided("user_id"), "aliases should be provided under the name")
+		})
+	}
+}
+
+func TestNamingStrategy(t *testing.T) {
+	tests := []struct {
+		name                string
+		snake, camel, kebab string
+	}{
+		{"UserID", "user_id", "userId", "user-id"},
+		{"HTTPServer", "http_server", "httpServer", "http-server"},
+		{"Page2Size", "page2_size", "page2Size", "page2-size"},
+		{"ID", "id", "id", "id"},
+		{"Name", "name", "name", "name"},
+		{"Snake_Case", "snake_case", "snakeCase", "snake-case"},
+		{"User__ID", "user_id", "userId", "user-id"},
+		{"User_", "user", "user", "user"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.snake, form.SnakeCase.Key(tt.name))
+		assert.Equal(t, tt.camel, form.CamelCase.Key(tt.name))
+		assert.Equal(t, tt.kebab, form.KebabCase.Key(tt.name))
+	}
+
+	type input struct {
+		UserID   int
+		PageSize int    `form:",default=20"`
+		Sort     string `query:"order"`
+		Ignored  string `form:"-"`
+		internal string
+	}
+	req := httptest.NewRequest("GET", "/?user_id=1&order=name&Ignored=x&internal=x", nil)
+	var output input
+	decoder := form.NewDecoder(req, form.WithNaming(form.SnakeCase))
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, input{UserID: 1, PageSize: 20, Sort: "name"}, output)
+
+	output = input{}
+	form.NewDecoder(req).Decode(&output)
+	assert.Equal(t, input{PageSize: 20, Sort: "name"}, output, "fields without tag should be skipped by default")
+
+	type schemaInput struct {
+		Limit int    `schema:"limit"`
+		Sort  string `query:"order"`
+	}
+	req = httptest.NewRequest("GET", "/?limit=10&order=name", nil)
+	var schemaOutput schemaInput
+	decoder = form.NewDecoder(req, form.WithTagName("schema"))
+	decoder.Decode(&schemaOutput)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, schemaInput{Limit: 10, Sort: "name"}, schemaOutput)
+}
+
+type pathInput struct {
+	ID   uint64 `path:"id" validate:"min=1"`
+	Slug string `path:"slug"`
+}
+
+type paramsKey struct{}
+
+type byName map[string]string
+
+func (p byName) ByName(name string) string {
+	return p[name]
+}
+
+func TestFormDecoderPath(t *testing.T) {
+	var output pathInput
+	var decodeErr error
+	mux := http.NewServeMux()
+	mux.HandleFunc("/items/{id}/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		output = pathInput{}
+		decodeErr = form.NewDecoder(r, form.WithPathErrorStatus(http.StatusNotFound)).Decode(&output)
+	})
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/items/42/foo", nil))
+	assert.Nil(t, decodeErr.(*problem.Input))
+	assert.Equal(t, pathInput{ID: 42, Slug: "foo"}, output)
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/items/abc/foo", nil))
+	assert.IsType(t, &problem.NotFoundProblem{}, decodeErr)
+
+	sources := []form.PathParamSource{
+		form.ContextParams{Key: paramsKey{}},
+		form.ContextParams{Key: "params"},
+		form.PathVarsFunc(func(*http.Request) map[string]string { return map[string]string{"id": "0", "slug": "foo"} }),
+		form.PathParamFunc(func(_ *http.Request, name string) string { return map[string]string{"id": "0", "slug": "foo"}[name] }),
+	}
+	req := httptest.NewRequest("GET", "/items/0/foo", nil)
+	ctx := context.WithValue(req.Context(), paramsKey{}, map[string]string{"id": "0", "slug": "foo"})
+	ctx = context.WithValue(ctx, "params", byName{"id": "0", "slug": "foo"})
+	req = req.WithContext(ctx)
+	for i, source := range sources {
+		output = pathInput{}
+		decoder := form.NewDecoder(req, form.WithPathParams(source))
+		decoder.Decode(&output)
+		assert.Equal(t, "foo", output.Slug, i)
+		if assert.NotNil(t, decoder.Input, i) {
+			assert.Equal(t, []problem.ParamError{
+				{Field: "id", Reason: "must be greater than or equal to 1", In: "path", Code: "out_of_range", MessageID: "validate.min", Params: map[string]interface{}{"min": uint64(1)}},
+			}, decoder.Input.InvalidParams, i)
+		}
+	}
+}
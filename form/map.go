@@ -0,0 +1,79 @@
+package form
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// isMap reports whether t is a map whose entries are decoded from the keys
+// nested into the map key, e.g meta[color]=red. Keys must be strings.
+func (c *compiler) isMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && !isUnmarshaler(t) && !c.config.hasConverter(t)
+}
+
+// extractMap decodes the entries of the map e from the keys nested into key,
+// their errors being reported on key[entry].
+func (d *Decoder) extractMap(key string, f *field, e reflect.Value) error {
+	prefix := d.requestKey(f.source, key) + "."
+	values := d.keyed(f.source)
+	var entries []string
+	for k := range values {
+		if strings.HasPrefix(k, prefix) && len(k) > len(prefix) {
+			entries = append(entries, k)
+		}
+	}
+	sort.Strings(entries)
+	if len(entries) == 0 {
+		if f.required {
+			d.addParamsReason(key, f.source, errRequired)
+		}
+		return nil
+	}
+	d.markProvided(key)
+	if f.maxKeys > 0 && len(entries) > f.maxKeys {
+		d.addParamsReason(key, f.source, message("form.max_keys", "max", f.maxKeys, "count", len(entries)))
+		return nil
+	}
+
+	m := reflect.MakeMapWithSize(e.Type(), len(entries))
+	valid := true
+	for _, k := range entries {
+		d.markLookup(f.source, k)
+		name := k[len(prefix):]
+		field := fmt.Sprintf("%s[%s]", key, name)
+		if f.keys != nil && !contains(f.keys, name) {
+			d.addParamsReason(field, f.source, message("form.key", "key", name, "expected", f.keys))
+			valid = false
+			continue
+		}
+		val := values[k][0]
+		v := reflect.New(e.Type().Elem()).Elem()
+		if val != "" || !f.allowEmpty {
+			ok, err := d.setFromType(v, field, val, f)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				valid = false
+				continue
+			}
+		}
+		m.SetMapIndex(reflect.ValueOf(name).Convert(e.Type().Key()), v)
+	}
+	if !valid {
+		return nil
+	}
+	e.Set(m)
+	return d.checkRules(key, f, e)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package form
+
+import "github.com/sganon/go-request/problem"
+
+// messages are the templates of the decoding errors in
+// problem.DefaultLanguage, see problem.RegisterMessages to translate them.
+var messages = map[string]string{
+	"form.input":             "Your form parameters could not be decoded",
+	"form.required":          "parameter is required",
+	"form.unknown":           "unknown parameter",
+	"form.unknown_suggested": `unknown parameter, did you mean "{suggestion}"?`,
+	"form.unmarshal_text":    "an error occured via UnmarshalText: {error}",
+	"form.int_syntax":        "syntax error: unable to convert to integer",
+	"form.uint_syntax":       "syntax error: unable to convert to unsigned integer",
+	"form.float_syntax":      "syntax error: unable to convert to float",
+	"form.bool_syntax":       "syntax error: unable to convert to bool",
+	"form.int_range":         "out of range: value must be between {min} and {max}",
+	"form.float_range":       "out of range: value does not fit in a {bits}-bit float",
+
+	"form.min_items": "expected at least {min} items, got {count}",
+	"form.max_items": "expected at most {max} items, got {count}",
+	"form.duplicate": `duplicate value "{value}", items must be unique`,
+	"form.max_keys":  "expected at most {max} keys, got {count}",
+	"form.key":       `key "{key}" is not allowed, expected one of: {expected}`,
+
+	"form.multipart":        "invalid multipart body: {error}",
+	"form.file_required":    "this file is required",
+	"form.file_read":        "unable to read file: {error}",
+	"form.file_size":        "file size must not exceed {max}",
+	"form.file_type":        "file type {type} is not allowed, expected one of: {expected}",
+	"form.max_files":        "expected at most {max} files, got {count}",
+	"form.unmarshal_binary": "an error occured via UnmarshalBinary: {error}",
+
+	"form.duration_syntax": "syntax error: expected a duration such as 1h30m",
+	"form.unix_syntax":     "syntax error: expected a unix timestamp in seconds",
+	"form.unix_milli":      "syntax error: expected a unix timestamp in milliseconds",
+	"form.time_syntax":     "syntax error: expected a time in format {layout}",
+	"form.date_syntax":     "syntax error: expected a date in format 2006-01-02",
+	"form.clock_syntax":    "syntax error: expected a time of day in format 15:04 or 15:04:05",
+	"form.time_zone":       `unknown time zone "{zone}"`,
+
+	"form.range_syntax":    "syntax error: expected a range such as 10..100, 10.. or ..100",
+	"form.range_time":      "syntax error: expected a time in RFC 3339 format or a date in format 2006-01-02",
+	"form.range_order":     "min must be at most max",
+	"form.range_operators": "a range cannot be given with operators",
+	"form.range_operator":  `unknown operator "{operator}", expected one of: {expected}`,
+	"form.range_bound":     "a bound cannot be given twice",
+	"form.range_value":     "operator {operator} requires a value",
+
+	"form.sort_syntax": "syntax error: expected fields such as -created_at,name",
+	"form.sort_twice":  `field "{field}" cannot be sorted twice`,
+	"form.sort_field":  `cannot sort by "{field}", expected one of: {expected}`,
+
+	"form.filter_syntax":     "syntax error at column {column}: {reason}",
+	"form.filter_unexpected": `unexpected "{token}"`,
+	"form.filter_string":     "unterminated string",
+	"form.filter_depth":      "expression is nested too deeply",
+	"form.filter_expected":   "expected {token}",
+	"form.filter_list":       "expected , or )",
+	"form.filter_name":       "expected a field",
+	"form.filter_value":      "expected a value",
+	"form.filter_op":         "expected an operator such as =, !=, >, >=, <, <=, ~ or in",
+	"form.filter_field":      `field "{field}" at column {column} cannot be filtered, expected one of: {expected}`,
+	"form.filter_operator":   `operator {operator} at column {column} is not allowed on "{field}", expected one of: {expected}`,
+
+	"form.fields_syntax":   "syntax error: expected fields such as id,name,owner.email",
+	"form.fields_empty":    "syntax error: empty field in path",
+	"form.fields_resource": `unknown resource "{resource}"`,
+	"form.fields_of":       "{resource}: {reason}",
+	"form.fields_unknown":  `unknown field "{field}", expected one of: {expected}`,
+	"form.fields_leaf":     `field "{field}" has no fields`,
+
+	"form.page_max_limit": "limit must be at most {max}",
+	"form.page_min":       "{param} must be at least {min}",
+	"form.page_cursor":    "page and cursor cannot be given together",
+	"form.page_too_large": "out of range: page is too large",
+	"form.cursor":         "invalid cursor",
+}
+
+// codes are the codes of the messages, see problem.ParamError.
+var codes = map[string]string{
+	"form.required":          problem.CodeRequired,
+	"form.unknown":           problem.CodeUnknownParameter,
+	"form.unknown_suggested": problem.CodeUnknownParameter,
+	"form.unmarshal_text":    problem.CodeInvalid,
+	"form.int_syntax":        problem.CodeTypeMismatch,
+	"form.uint_syntax":       problem.CodeTypeMismatch,
+	"form.float_syntax":      problem.CodeTypeMismatch,
+	"form.bool_syntax":       problem.CodeTypeMismatch,
+	"form.int_range":         problem.CodeOutOfRange,
+	"form.float_range":       problem.CodeOutOfRange,
+
+	"form.min_items": problem.CodeLength,
+	"form.max_items": problem.CodeLength,
+	"form.duplicate": problem.CodeDuplicate,
+	"form.max_keys":  problem.CodeLength,
+	"form.key":       problem.CodeInvalidValue,
+
+	"form.multipart":        problem.CodeInvalid,
+	"form.file_required":    problem.CodeRequired,
+	"form.file_read":        problem.CodeInvalid,
+	"form.file_size":        problem.CodeOutOfRange,
+	"form.file_type":        problem.CodeInvalidValue,
+	"form.max_files":        problem.CodeLength,
+	"form.unmarshal_binary": problem.CodeInvalid,
+
+	"form.duration_syntax": problem.CodeInvalidFormat,
+	"form.unix_syntax":     problem.CodeInvalidFormat,
+	"form.unix_milli":      problem.CodeInvalidFormat,
+	"form.time_syntax":     problem.CodeInvalidFormat,
+	"form.date_syntax":     problem.CodeInvalidFormat,
+	"form.clock_syntax":    problem.CodeInvalidFormat,
+	"form.time_zone":       problem.CodeInvalidValue,
+
+	"form.range_syntax":    problem.CodeInvalidFormat,
+	"form.range_time":      problem.CodeInvalidFormat,
+	"form.range_order":     problem.CodeInvalidValue,
+	"form.range_operators": problem.CodeConflict,
+	"form.range_operator":  problem.CodeInvalidValue,
+	"form.range_bound":     problem.CodeDuplicate,
+	"form.range_value":     problem.CodeInvalidFormat,
+
+	"form.sort_syntax": problem.CodeInvalidFormat,
+	"form.sort_twice":  problem.CodeDuplicate,
+	"form.sort_field":  problem.CodeInvalidValue,
+
+	"form.filter_syntax":     problem.CodeInvalidFormat,
+	"form.filter_unexpected": problem.CodeInvalidFormat,
+	"form.filter_string":     problem.CodeInvalidFormat,
+	"form.filter_depth":      problem.CodeInvalidFormat,
+	"form.filter_expected":   problem.CodeInvalidFormat,
+	"form.filter_list":       problem.CodeInvalidFormat,
+	"form.filter_name":       problem.CodeInvalidFormat,
+	"form.filter_value":      problem.CodeInvalidFormat,
+	"form.filter_op":         problem.CodeInvalidFormat,
+	"form.filter_field":      problem.CodeInvalidValue,
+	"form.filter_operator":   problem.CodeInvalidValue,
+
+	"form.fields_syntax":   problem.CodeInvalidFormat,
+	"form.fields_empty":    problem.CodeInvalidFormat,
+	"form.fields_resource": problem.CodeInvalidValue,
+	"form.fields_of":       problem.CodeInvalidValue,
+	"form.fields_unknown":  problem.CodeInvalidValue,
+	"form.fields_leaf":     problem.CodeInvalidValue,
+
+	"form.page_max_limit": problem.CodeOutOfRange,
+	"form.page_min":       problem.CodeOutOfRange,
+	"form.page_cursor":    problem.CodeConflict,
+	"form.page_too_large": problem.CodeOutOfRange,
+	"form.cursor":         problem.CodeInvalid,
+}
+
+// Messages shared by the conversions.
+var (
+	errRequired    = message("form.required")
+	errIntSyntax   = message("form.int_syntax", "expected_type", "integer")
+	errUintSyntax  = message("form.uint_syntax", "expected_type", "unsigned_integer")
+	errFloatSyntax = message("form.float_syntax", "expected_type", "float")
+	errBoolSyntax  = message("form.bool_syntax", "expected_type", "boolean")
+)
+
+func init() {
+	problem.RegisterMessages(problem.DefaultLanguage, messages)
+}
+
+// message returns the message id with params given as key value pairs.
+func message(id string, params ...interface{}) *problem.Message {
+	msg := &problem.Message{ID: id, Code: codes[id]}
+	if len(params) > 0 {
+		msg.Params = make(map[string]interface{}, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			msg.Params[params[i].(string)] = params[i+1]
+		}
+	}
+	return msg
+}
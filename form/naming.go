@@ -0,0 +1,70 @@
+package form
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy derives the key of a field from its name when it has no
+// tag, or a tag without name such as `form:",required"`.
+type NamingStrategy int
+
+// Naming strategies, e.g for a UserID field: user_id with SnakeCase, userId
+// with CamelCase and user-id with KebabCase. With NoNaming, the default,
+// fields without tag are skipped.
+const (
+	NoNaming NamingStrategy = iota
+	SnakeCase
+	CamelCase
+	KebabCase
+)
+
+// Key returns the key of the field name.
+func (n NamingStrategy) Key(name string) string {
+	words := splitWords(name)
+	switch n {
+	case SnakeCase:
+		return strings.ToLower(strings.Join(words, "_"))
+	case CamelCase:
+		for i, word := range words {
+			word = strings.ToLower(word)
+			if i > 0 {
+				word = strings.ToUpper(word[:1]) + word[1:]
+			}
+			words[i] = word
+		}
+		return strings.Join(words, "")
+	case KebabCase:
+		return strings.ToLower(strings.Join(words, "-"))
+	}
+	return name
+}
+
+// splitWords splits a Go identifier into its words, acronyms being kept
+// together: HTTPServerID gives HTTP, Server and ID.
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		next := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		switch {
+		case cur == '_':
+			// consecutive or trailing underscores would give empty words
+			if start < i {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+		case unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && next)):
+			if start < i {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
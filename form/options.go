@@ -0,0 +1,52 @@
+package form
+
+import (
+	"strings"
+)
+
+// flagOptions lists the tag options which do not take a value.
+var flagOptions = map[string]bool{
+	"required":   true,
+	"unique":     true,
+	"allowempty": true,
+	"omitempty":  true,
+}
+
+// tagOptions maps the options of a tag to their value, e.g
+// `form:"ids,required,sep=|"` gives {"required": "", "sep": "|"}.
+type tagOptions map[string]string
+
+// parseOptions parses the options of a tag. As options are comma separated,
+// a value containing commas is split by the tag parsing: an option which is
+// neither a flag nor a key=value pair is thus joined back to the previous value.
+func parseOptions(options []string) tagOptions {
+	opts := make(tagOptions, len(options))
+	var last string
+	for _, option := range options {
+		i := strings.Index(option, "=")
+		if i < 0 && last != "" && !flagOptions[option] {
+			opts[last] += "," + option
+			continue
+		}
+		if i < 0 {
+			opts[option] = ""
+			last = ""
+			continue
+		}
+		last = option[:i]
+		opts[last] = option[i+1:]
+	}
+	return opts
+}
+
+// Has reports whether the option name is set.
+func (o tagOptions) Has(name string) bool {
+	_, ok := o[name]
+	return ok
+}
+
+// Get returns the value of the option name and whether it is set.
+func (o tagOptions) Get(name string) (string, bool) {
+	v, ok := o[name]
+	return v, ok
+}
@@ -0,0 +1,271 @@
+package form
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var paginationType = reflect.TypeOf(Pagination{})
+
+// Keys of the parameters of a Pagination, nested into the key of its field
+// when it has one, e.g `form:"comments"` reads comments[page].
+const (
+	PageParam   = "page"
+	LimitParam  = "limit"
+	CursorParam = "cursor"
+)
+
+// defaultLimit is the limit of a Pagination without default option, lowered
+// to its maxlimit option.
+const defaultLimit = 20
+
+// cursorMACSize is the size of the truncated HMAC-SHA256 of a cursor.
+const cursorMACSize = 16
+
+var errInvalidCursor = message("form.cursor")
+
+// Pagination is decoded from either page and limit or cursor and limit, e.g
+// ?page=2&limit=50. The maxlimit option bounds the limit, the default option
+// giving the limit when none is given. Cursors are opaque tokens signed with
+// the CursorKey of the decoder, so that clients cannot forge their offsets:
+// without key, cursors are rejected.
+type Pagination struct {
+	// Page is the 1-based number of the page, 0 when a cursor is given.
+	Page int
+	// Limit is the maximum number of items of the page.
+	Limit int
+	// Offset is the number of items before the page.
+	Offset int
+	// Cursor is the cursor given in the request, if any.
+	Cursor string
+
+	key     []byte // signs the cursors of the links
+	base    string // key of the parameters in the request
+	cursors bool   // whether the links use cursors rather than pages
+}
+
+// NextCursor returns the cursor of the page following p, empty if p was not
+// decoded with a CursorKey.
+func (p Pagination) NextCursor() string {
+	if len(p.key) == 0 {
+		return ""
+	}
+	return signCursor(p.key, p.Offset+p.Limit)
+}
+
+// Links returns the Link header of the pages next to p, see RFC 8288, u being
+// the URL of the current request. The previous page is linked unless p is the
+// first one, the next page if hasNext reports there are items after p.
+// Links use cursors when a cursor was given, or when the decoder has a
+// CursorKey and no page was given.
+func (p Pagination) Links(u *url.URL, hasNext bool) string {
+	var links []string
+	if p.cursors {
+		if p.Offset > 0 {
+			prev := p.Offset - p.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			links = append(links, p.link(u, CursorParam, signCursor(p.key, prev), "prev"))
+		}
+		if hasNext {
+			links = append(links, p.link(u, CursorParam, p.NextCursor(), "next"))
+		}
+		return strings.Join(links, ", ")
+	}
+	page := p.Page
+	if page < 1 {
+		page = 1
+	}
+	if page > 1 {
+		links = append(links, p.link(u, PageParam, strconv.Itoa(page-1), "prev"))
+	}
+	if hasNext {
+		links = append(links, p.link(u, PageParam, strconv.Itoa(page+1), "next"))
+	}
+	return strings.Join(links, ", ")
+}
+
+// SetLinks sets the Link header of w from the URL of r, see Links.
+func (p Pagination) SetLinks(w http.ResponseWriter, r *http.Request, hasNext bool) {
+	if links := p.Links(r.URL, hasNext); links != "" {
+		w.Header().Set("Link", links)
+	}
+}
+
+// link returns the link of rel, u with its page and cursor replaced by
+// name=value.
+func (p Pagination) link(u *url.URL, name, value, rel string) string {
+	query := u.Query()
+	for key := range query {
+		switch normalizeKey(key) {
+		case joinKey(p.base, PageParam), joinKey(p.base, CursorParam), joinKey(p.base, LimitParam):
+			delete(query, key)
+		}
+	}
+	query.Set(joinKey(p.base, name), value)
+	query.Set(joinKey(p.base, LimitParam), strconv.Itoa(p.Limit))
+	target := *u
+	target.RawQuery = query.Encode()
+	return fmt.Sprintf("<%s>; rel=%q", target.String(), rel)
+}
+
+// MarshalValues implements ValuesMarshaler
+func (p Pagination) MarshalValues() (url.Values, error) {
+	values := url.Values{}
+	if p.Cursor != "" {
+		values.Set(CursorParam, p.Cursor)
+	} else if p.Page > 0 {
+		values.Set(PageParam, strconv.Itoa(p.Page))
+	}
+	if p.Limit > 0 {
+		values.Set(LimitParam, strconv.Itoa(p.Limit))
+	}
+	return values, nil
+}
+
+// signCursor returns the opaque cursor of offset signed with key.
+func signCursor(key []byte, offset int) string {
+	payload := []byte(strconv.Itoa(offset))
+	return base64.RawURLEncoding.EncodeToString(append(payload, cursorMAC(key, payload)...))
+}
+
+// verifyCursor returns the offset of cursor if it is signed with key.
+func verifyCursor(key []byte, cursor string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if len(key) == 0 || err != nil || len(b) <= cursorMACSize {
+		return 0, errInvalidCursor
+	}
+	payload, mac := b[:len(b)-cursorMACSize], b[len(b)-cursorMACSize:]
+	if !hmac.Equal(mac, cursorMAC(key, payload)) {
+		return 0, errInvalidCursor
+	}
+	offset, err := strconv.Atoi(string(payload))
+	if err != nil || offset < 0 {
+		return 0, errInvalidCursor
+	}
+	return offset, nil
+}
+
+func cursorMAC(key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)[:cursorMACSize]
+}
+
+// extractPagination decodes the pagination e from the page, limit and cursor
+// keys nested into key.
+func (d *Decoder) extractPagination(key string, f *field, e reflect.Value) error {
+	p := Pagination{
+		Limit: f.defLimit,
+		key:   d.CursorKey,
+		base:  d.requestKey(f.source, key),
+	}
+	valid := true
+	limit, ok := d.paginationParam(key, p.base, LimitParam, f)
+	if ok {
+		if p.Limit, ok = d.paginationInt(key, LimitParam, limit, f); ok && f.maxLimit > 0 && p.Limit > f.maxLimit {
+			d.addParamsReason(joinKey(key, LimitParam), f.source, message("form.page_max_limit", "max", f.maxLimit))
+			ok = false
+		}
+		valid = valid && ok
+	}
+	page, hasPage := d.paginationParam(key, p.base, PageParam, f)
+	cursor, hasCursor := d.paginationParam(key, p.base, CursorParam, f)
+	switch {
+	case hasPage && hasCursor:
+		d.addParamsReason(joinKey(key, CursorParam), f.source, message("form.page_cursor"))
+		valid = false
+	case hasCursor:
+		offset, err := verifyCursor(d.CursorKey, cursor)
+		if err != nil {
+			d.addParamsReason(joinKey(key, CursorParam), f.source, err)
+			valid = false
+		}
+		p.Cursor, p.Offset, p.cursors = cursor, offset, true
+	default:
+		p.Page = 1
+		if hasPage {
+			p.Page, ok = d.paginationInt(key, PageParam, page, f)
+			valid = valid && ok
+		}
+		p.cursors = len(d.CursorKey) > 0 && !hasPage
+	}
+	if !valid {
+		return nil
+	}
+	if p.Page > 0 {
+		if maxInt := int(^uint(0) >> 1); p.Page-1 > maxInt/p.Limit {
+			d.addParamsReason(joinKey(key, PageParam), f.source, message("form.page_too_large"))
+			return nil
+		}
+		p.Offset = (p.Page - 1) * p.Limit
+	}
+	e.Set(reflect.ValueOf(p))
+	return nil
+}
+
+// paginationParam returns the value of the parameter name of a pagination
+// field, base being its key in the request. Empty values are not given.
+func (d *Decoder) paginationParam(key, base, name string, f *field) (string, bool) {
+	requestKey := joinKey(base, name)
+	d.markLookup(f.source, requestKey)
+	vals, ok := d.lookupKey(f.source, requestKey)
+	if !ok {
+		return "", false
+	}
+	d.markProvided(joinKey(key, name))
+	if vals[0] == "" {
+		return "", false
+	}
+	return vals[0], true
+}
+
+// paginationInt converts the page or limit val, it reports whether it is a
+// positive integer.
+func (d *Decoder) paginationInt(key, name, val string, f *field) (int, bool) {
+	n, err := strconv.Atoi(val)
+	var reason error = message("form.page_min", "param", name, "min", 1)
+	if err != nil {
+		reason = errIntSyntax
+	} else if n >= 1 {
+		return n, true
+	}
+	d.addParamsReason(joinKey(key, name), f.source, reason)
+	return 0, false
+}
+
+// compilePaginationOptions parses the maxlimit and default options of a
+// Pagination field.
+func (f *field) compilePaginationOptions(t reflect.Type) error {
+	if t != paginationType {
+		return &TagError{Option: f.source, Err: fmt.Errorf("unsupported type %s, use form.Pagination", t)}
+	}
+	if !isKeyed(f.source) {
+		return &TagError{Option: f.source, Err: fmt.Errorf("%s cannot be decoded from %s", t, f.source)}
+	}
+	var err error
+	if f.maxLimit, err = intOption(f.opts, "maxlimit"); err != nil {
+		return &TagError{Option: "maxlimit", Err: err}
+	}
+	f.defLimit = defaultLimit
+	if f.maxLimit > 0 && f.maxLimit < f.defLimit {
+		f.defLimit = f.maxLimit
+	}
+	if f.hasDef {
+		n, err := strconv.Atoi(f.def)
+		if err != nil || n < 1 || (f.maxLimit > 0 && n > f.maxLimit) {
+			return &TagError{Option: "default", Err: fmt.Errorf("invalid default limit %q", f.def)}
+		}
+		f.defLimit = n
+	}
+	f.kind = paginationField
+	return nil
+}
@@ -0,0 +1,119 @@
+package form_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sganon/go-request/form"
+	"github.com/sganon/go-request/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+type paginationInput struct {
+	form.Pagination `query:",maxlimit=100,default=50"`
+	Comments        form.Pagination `query:"comments,maxlimit=10"`
+}
+
+var cursorKey = []byte("secret")
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		query  string
+		page   int
+		limit  int
+		offset int
+		err    []problem.ParamError
+	}{
+		{query: "", page: 1, limit: 50},
+		{query: "page=3&limit=20", page: 3, limit: 20, offset: 40},
+		{query: "limit=101", err: []problem.ParamError{{Field: "limit", Reason: "limit must be at most 100", In: "query", Code: "out_of_range", MessageID: "form.page_max_limit", Params: map[string]interface{}{"max": 100}}}},
+		{query: "limit=0", err: []problem.ParamError{{Field: "limit", Reason: "limit must be at least 1", In: "query", Code: "out_of_range", MessageID: "form.page_min", Params: map[string]interface{}{"param": "limit", "min": 1}}}},
+		{query: "page=a", err: []problem.ParamError{{Field: "page", Reason: "syntax error: unable to convert to integer", In: "query", Code: "type_mismatch", MessageID: "form.int_syntax", Params: map[string]interface{}{"expected_type": "integer"}}}},
+		{query: "page=2&cursor=abc", err: []problem.ParamError{{Field: "cursor", Reason: "page and cursor cannot be given together", In: "query", Code: "conflict", MessageID: "form.page_cursor"}}},
+		{query: "cursor=abc", err: []problem.ParamError{{Field: "cursor", Reason: "invalid cursor", In: "query", Code: "invalid", MessageID: "form.cursor"}}},
+		{query: "page=9223372036854775807", err: []problem.ParamError{{Field: "page", Reason: "out of range: page is too large", In: "query", Code: "out_of_range", MessageID: "form.page_too_large"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var output paginationInput
+			decoder := form.NewDecoder(httptest.NewRequest("GET", "/?"+tt.query, nil))
+			decoder.Decode(&output)
+			if tt.err != nil {
+				if assert.NotNil(t, decoder.Input) {
+					assert.Equal(t, tt.err, decoder.Input.InvalidParams)
+				}
+				return
+			}
+			assert.Nil(t, decoder.Input)
+			assert.Equal(t, tt.page, output.Page)
+			assert.Equal(t, tt.limit, output.Limit)
+			assert.Equal(t, tt.offset, output.Offset)
+			assert.Equal(t, 10, output.Comments.Limit, "the default limit should be lowered to maxlimit")
+		})
+	}
+
+	var output paginationInput
+	decoder := form.NewDecoder(httptest.NewRequest("GET", "/?comments[page]=2&comments[limit]=5", nil))
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, 5, output.Comments.Offset)
+	assert.True(t, decoder.Provided("comments.page"))
+	assert.Equal(t, `</items?comments.limit=5&comments.page=1&q=a>; rel="prev", </items?comments.limit=5&comments.page=3&q=a>; rel="next"`,
+		output.Comments.Links(&url.URL{Path: "/items", RawQuery: "q=a&comments[page]=2"}, true))
+	assert.Equal(t, "", output.Links(&url.URL{Path: "/items"}, false), "the only page has no link")
+
+	values, err := form.NewEncoder().Encode(output)
+	assert.NoError(t, err)
+	assert.Equal(t, "comments.limit=5&comments.page=2&limit=50&page=1", values.Encode())
+}
+
+func TestPaginationCursor(t *testing.T) {
+	var first paginationInput
+	decoder := form.NewDecoder(httptest.NewRequest("GET", "/items?limit=10", nil), form.WithCursorKey(cursorKey))
+	decoder.Decode(&first)
+	assert.Nil(t, decoder.Input)
+	cursor := first.NextCursor()
+	assert.NotEmpty(t, cursor)
+
+	var next paginationInput
+	decoder = form.NewDecoder(httptest.NewRequest("GET", "/items?limit=10&cursor="+cursor, nil), form.WithCursorKey(cursorKey))
+	decoder.Decode(&next)
+	assert.Nil(t, decoder.Input)
+	assert.Equal(t, 0, next.Page)
+	assert.Equal(t, 10, next.Offset)
+	assert.Equal(t, cursor, next.Cursor)
+
+	w := httptest.NewRecorder()
+	next.SetLinks(w, httptest.NewRequest("GET", "/items?limit=10&cursor="+cursor, nil), true)
+	links := strings.Split(w.Header().Get("Link"), ", ")
+	if assert.Len(t, links, 2) {
+		assert.Equal(t, `</items?cursor=`+next.NextCursor()+`&limit=10>; rel="next"`, links[1])
+		prev := strings.TrimSuffix(strings.TrimPrefix(links[0], "<"), `>; rel="prev"`)
+		var output paginationInput
+		decoder = form.NewDecoder(httptest.NewRequest("GET", prev, nil), form.WithCursorKey(cursorKey))
+		decoder.Decode(&output)
+		assert.Nil(t, decoder.Input)
+		assert.Equal(t, 0, output.Offset, "the previous link should go back to the first page")
+	}
+
+	for _, tampered := range []string{cursor[:len(cursor)-1] + "A", "MjA" + cursor[3:]} {
+		decoder = form.NewDecoder(httptest.NewRequest("GET", "/items?cursor="+tampered, nil), form.WithCursorKey(cursorKey))
+		decoder.Decode(&next)
+		if assert.NotNil(t, decoder.Input, tampered) {
+			assert.Equal(t, []problem.ParamError{{Field: "cursor", Reason: "invalid cursor", In: "query", Code: "invalid", MessageID: "form.cursor"}}, decoder.Input.InvalidParams)
+		}
+	}
+
+	decoder = form.NewDecoder(httptest.NewRequest("GET", "/items?cursor="+cursor, nil), form.WithCursorKey([]byte("other")))
+	decoder.Decode(&next)
+	assert.NotNil(t, decoder.Input, "a cursor signed with another key should be rejected")
+
+	assert.Error(t, form.Compile(struct {
+		Limit int `query:"limit,maxlimit=10"`
+	}{}), "only form.Pagination accepts the maxlimit option")
+	assert.Error(t, form.Compile(struct {
+		form.Pagination `query:",maxlimit=10,default=20"`
+	}{}), "the default limit should not exceed maxlimit")
+}
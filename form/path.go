@@ -0,0 +1,69 @@
+package form
+
+import (
+	"net/http"
+)
+
+// PathParamSource gives the path parameters of a request, those are
+// usually set by the router.
+type PathParamSource interface {
+	// PathParam returns the value of the path parameter name and whether
+	// it is set.
+	PathParam(r *http.Request, name string) (string, bool)
+}
+
+// ServeMuxParams reads the path parameters of the wildcards of a
+// http.ServeMux pattern, such as /items/{id}, with http.Request.PathValue.
+// It is the default PathParamSource of a Decoder, it never finds any
+// parameter before Go 1.22.
+type ServeMuxParams struct{}
+
+// PathParam implements PathParamSource
+func (ServeMuxParams) PathParam(r *http.Request, name string) (string, bool) {
+	// Asserted so that the package still builds before Go 1.22
+	pv, ok := interface{}(r).(interface{ PathValue(string) string })
+	if !ok {
+		return "", false
+	}
+	v := pv.PathValue(name)
+	return v, v != ""
+}
+
+// ContextParams reads the path parameters stored in the request context
+// under Key. The stored value can be a map[string]string or have a
+// ByName(string) string method, as httprouter.Params does.
+type ContextParams struct {
+	Key interface{}
+}
+
+// PathParam implements PathParamSource
+func (c ContextParams) PathParam(r *http.Request, name string) (string, bool) {
+	switch params := r.Context().Value(c.Key).(type) {
+	case map[string]string:
+		v, ok := params[name]
+		return v, ok
+	case interface{ ByName(string) string }:
+		v := params.ByName(name)
+		return v, v != ""
+	}
+	return "", false
+}
+
+// PathParamFunc adapts a function such as chi.URLParam to a PathParamSource.
+type PathParamFunc func(r *http.Request, name string) string
+
+// PathParam implements PathParamSource
+func (f PathParamFunc) PathParam(r *http.Request, name string) (string, bool) {
+	v := f(r, name)
+	return v, v != ""
+}
+
+// PathVarsFunc adapts a function returning all the path parameters of a
+// request, such as mux.Vars, to a PathParamSource.
+type PathVarsFunc func(r *http.Request) map[string]string
+
+// PathParam implements PathParamSource
+func (f PathVarsFunc) PathParam(r *http.Request, name string) (string, bool) {
+	v, ok := f(r)[name]
+	return v, ok
+}
@@ -0,0 +1,536 @@
+package form
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/fatih/structtag"
+	"github.com/sganon/go-request/validate"
+)
+
+// plans caches the decoding plan of each struct type
+var plans sync.Map
+
+// valueOptions lists the tag options which take a value, see flagOptions.
+var valueOptions = map[string]bool{
+	"default":    true,
+	"sep":        true,
+	"minitems":   true,
+	"maxitems":   true,
+	"layout":     true,
+	"tz":         true,
+	"maxsize":    true,
+	"maxfiles":   true,
+	"types":      true,
+	"keys":       true,
+	"maxkeys":    true,
+	"prefix":     true,
+	"sortable":   true,
+	"maxlimit":   true,
+	"filterable": true,
+	"resource":   true,
+}
+
+type fieldKind int
+
+const (
+	scalarField fieldKind = iota
+	sliceField
+	nestedField
+	fileField
+	mapField
+	valuesField
+	paginationField
+)
+
+// plan is the decoding plan of a struct type: its tagged fields with their
+// parsed tags, built once per type.
+type plan struct {
+	fields []*field
+}
+
+// field is a tagged field of a plan.
+type field struct {
+	index   []int // path from the decoded struct, see reflect.Value.FieldByIndex
+	source  string
+	name    string
+	aliases []string // keys looked up when name is not given
+	kind    fieldKind
+	opts    tagOptions
+	rules   validate.Rules
+	nested  *plan
+
+	required   bool
+	allowEmpty bool
+	def        string
+	hasDef     bool
+	sep        string
+	minItems   int
+	maxItems   int
+	layout     string
+	loc        *time.Location
+	maxSize    int64
+	maxFiles   int
+	types      []string
+	keys       []string
+	maxKeys    int
+	sortable   []string
+	maxLimit   int
+	defLimit   int
+	filterable filterChecker
+	resource   string
+}
+
+// Compile builds and caches the decoding plan of the struct type of v, which
+// can be a struct, a pointer to a struct or their reflect.Type. It returns a
+// *TagError for invalid tags, validation rules included, and unsupported field
+// types, calling it at startup reports them before any request is decoded.
+// The plan depends on the tag name, naming and converters of the decoder,
+// given by opts as to NewDecoder.
+func Compile(v interface{}, opts ...Option) error {
+	t, ok := v.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(v)
+	}
+	if t == nil {
+		return errors.New("form: cannot compile nil")
+	}
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("form: cannot compile %s, a struct is expected", t)
+	}
+	d := &Decoder{}
+	for _, opt := range opts {
+		opt(d)
+	}
+	_, err := planOf(t, d.planConfig())
+	return err
+}
+
+// planConfig holds the decoder settings a plan depends on besides its type.
+type planConfig struct {
+	// tag is the name of the tag of the form source.
+	tag    string
+	naming NamingStrategy
+	// converters are the converters of the decoder, their types being
+	// decoded as scalars. Plans are cached by convertersKey.
+	converters    map[reflect.Type]Converter
+	convertersKey string
+}
+
+// newPlanConfig returns the config of a tag name, the form tag if empty.
+func newPlanConfig(tag string, naming NamingStrategy) planConfig {
+	if tag == "" {
+		tag = SourceForm
+	}
+	return planConfig{tag: tag, naming: naming}
+}
+
+// planConfig returns the config of the plans of the decoder.
+func (d *Decoder) planConfig() planConfig {
+	config := newPlanConfig(d.TagName, d.Naming)
+	config.converters, config.convertersKey = d.converters, d.convertersKey
+	return config
+}
+
+// hasConverter reports whether t, or the type it points to, has a converter
+// registered on the decoder or by default.
+func (c planConfig) hasConverter(t reflect.Type) bool {
+	if _, ok := c.converters[t]; ok {
+		return true
+	}
+	if _, ok := c.converters[indirectType(t)]; ok {
+		return true
+	}
+	return hasConverter(t)
+}
+
+// planKey is the key of a cached plan.
+type planKey struct {
+	t          reflect.Type
+	tag        string
+	naming     NamingStrategy
+	converters string
+}
+
+// planOf returns the cached plan of the struct type t, compiling it if needed.
+func planOf(t reflect.Type, config planConfig) (*plan, error) {
+	key := planKey{t: t, tag: config.tag, naming: config.naming, converters: config.convertersKey}
+	if p, ok := plans.Load(key); ok {
+		return p.(*plan), nil
+	}
+	c := &compiler{config: config, compiling: map[reflect.Type]*plan{}}
+	p, err := c.compile(t)
+	if err != nil {
+		return nil, err
+	}
+	cached, _ := plans.LoadOrStore(key, p)
+	return cached.(*plan), nil
+}
+
+// compiler builds the plans of a config, compiling holds the plans being
+// built so that recursive types are supported.
+type compiler struct {
+	config    planConfig
+	compiling map[reflect.Type]*plan
+}
+
+// compile builds the plan of t.
+func (c *compiler) compile(t reflect.Type) (*plan, error) {
+	if p, ok := c.compiling[t]; ok {
+		return p, nil
+	}
+	p := &plan{}
+	c.compiling[t] = p
+	fields, err := c.compileFields(t, nil, "", map[reflect.Type]bool{t: true})
+	if err != nil {
+		return nil, err
+	}
+	p.fields, err = promote(fields)
+	return p, err
+}
+
+// compileFields compiles the fields of t, index being the path of t from the
+// compiled struct. The fields of embedded structs are compiled as its own,
+// their keys being prefixed with the prefix option of the embedded struct.
+// embedding holds the embedded types being compiled to stop on recursive ones.
+func (c *compiler) compileFields(t reflect.Type, index []int, prefix string, embedding map[reflect.Type]bool) ([]*field, error) {
+	var fields []*field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		path := append(append([]int(nil), index...), i)
+		tag, source, err := c.inputTag(sf)
+		if err != nil {
+			return nil, withField(err, sf.Name)
+		}
+		if sf.Anonymous && (tag == nil || tag.Name == "") && c.isNested(sf.Type) {
+			if sf.PkgPath != "" && sf.Type.Kind() == reflect.Ptr {
+				// Unexported pointers cannot be allocated
+				continue
+			}
+			embedPrefix, err := embeddedPrefix(tag)
+			if err != nil {
+				return nil, withField(err, sf.Name)
+			}
+			et := indirectType(sf.Type)
+			if embedding[et] {
+				continue
+			}
+			embedding[et] = true
+			embedded, err := c.compileFields(et, path, prefix+embedPrefix, embedding)
+			delete(embedding, et)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+		f, err := c.compileField(sf, tag, source)
+		if err != nil {
+			return nil, withField(err, sf.Name)
+		}
+		if f == nil {
+			continue
+		}
+		f.index = path
+		f.name = prefix + f.name
+		for i := range f.aliases {
+			f.aliases[i] = prefix + f.aliases[i]
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// withField sets the field of a TagError which has none.
+func withField(err error, field string) error {
+	if tagErr, ok := err.(*TagError); ok && tagErr.Field == "" {
+		tagErr.Field = field
+	}
+	return err
+}
+
+// embeddedPrefix returns the prefix option of the tag of an embedded struct,
+// which is the only option it accepts.
+func embeddedPrefix(tag *structtag.Tag) (string, error) {
+	if tag == nil {
+		return "", nil
+	}
+	opts := parseOptions(tag.Options)
+	for name := range opts {
+		if name != "prefix" {
+			return "", &TagError{Option: name, Err: errors.New("embedded structs only accept the prefix option")}
+		}
+	}
+	prefix, _ := opts.Get("prefix")
+	return prefix, nil
+}
+
+// promote applies the rules of Go to the keys of a source defined by several
+// fields: the least nested field wins, several embedded fields at the same
+// depth giving a TagError as the key is ambiguous.
+func promote(fields []*field) ([]*field, error) {
+	depths := make(map[aliasKey]int, len(fields))
+	counts := make(map[aliasKey]int, len(fields))
+	for _, f := range fields {
+		key := aliasKey{f.source, f.name}
+		depth, ok := depths[key]
+		switch {
+		case !ok || len(f.index) < depth:
+			depths[key], counts[key] = len(f.index), 1
+		case len(f.index) == depth:
+			counts[key]++
+		}
+	}
+	promoted := fields[:0]
+	for _, f := range fields {
+		key := aliasKey{f.source, f.name}
+		if len(f.index) != depths[key] {
+			continue
+		}
+		if counts[key] > 1 && len(f.index) > 1 {
+			return nil, &TagError{Field: f.name, Option: f.source, Err: errors.New("ambiguous key defined by several embedded structs")}
+		}
+		promoted = append(promoted, f)
+	}
+	return promoted, nil
+}
+
+// tagName returns the name of the tag of source.
+func (c *compiler) tagName(source string) string {
+	if source == SourceForm {
+		return c.config.tag
+	}
+	return source
+}
+
+// inputTag returns the input tag of sf and its source, nil if it has none.
+func (c *compiler) inputTag(sf reflect.StructField) (*structtag.Tag, string, error) {
+	tags, err := structtag.Parse(string(sf.Tag))
+	if err != nil {
+		return nil, "", &TagError{Err: err}
+	}
+	var tag *structtag.Tag
+	var source string
+	for _, s := range sources {
+		name := c.tagName(s)
+		if s != SourceForm && name == c.config.tag {
+			// The tag is read as the one of the form source
+			continue
+		}
+		t, err := tags.Get(name)
+		if err != nil {
+			continue
+		}
+		if tag != nil {
+			return nil, "", &TagError{Option: name, Err: fmt.Errorf("a field cannot have both %s and %s tags", tag.Key, name)}
+		}
+		tag, source = t, s
+	}
+	return tag, source, nil
+}
+
+// compileField compiles the input tag of sf, it returns nil if sf has no
+// input tag and no key is derived from its name, or if its tag name is "-".
+func (c *compiler) compileField(sf reflect.StructField, tag *structtag.Tag, source string) (*field, error) {
+	if tag == nil && sf.Anonymous && sf.Type == paginationType {
+		// An embedded Pagination reads its keys from the form source
+		tag, source = &structtag.Tag{Key: c.config.tag}, SourceForm
+	}
+	if tag == nil {
+		if c.config.naming == NoNaming || sf.PkgPath != "" || sf.Anonymous {
+			// Skip if field has no input tag
+			return nil, nil
+		}
+		tag, source = &structtag.Tag{Key: c.config.tag}, SourceForm
+	}
+	if tag.Name == "-" {
+		return nil, nil
+	}
+	if tag.Name == "" && indirectType(sf.Type) != paginationType {
+		tag.Name = c.config.naming.Key(sf.Name)
+	}
+	f, err := c.compileTag(sf, tag, source)
+	return f, withField(err, tag.Name)
+}
+
+// compileTag compiles the input tag of sf, read for source.
+func (c *compiler) compileTag(sf reflect.StructField, tag *structtag.Tag, source string) (*field, error) {
+	var err error
+	names := strings.Split(tag.Name, "|")
+	f := &field{
+		source:  source,
+		name:    names[0],
+		aliases: names[1:],
+		opts:    parseOptions(tag.Options),
+		sep:     ",",
+	}
+	for name := range f.opts {
+		if !flagOptions[name] && !valueOptions[name] {
+			return nil, &TagError{Option: name, Err: errors.New("unknown option")}
+		}
+	}
+	if f.opts.Has("prefix") {
+		return nil, &TagError{Option: "prefix", Err: errors.New("only embedded structs accept the prefix option")}
+	}
+	if f.rules, err = validate.Parse(sf.Tag.Get("validate")); err != nil {
+		return nil, &TagError{Option: "validate", Err: err}
+	}
+	f.required = f.opts.Has("required") || f.rules.Has("required")
+	// The decoder checks the key is given, the rule would reject given zero
+	// values such as ?count=0
+	f.rules = f.rules.Without("required")
+	if err := f.rules.Check(sf.Type); err != nil {
+		return nil, &TagError{Option: "validate", Err: err}
+	}
+	f.allowEmpty = f.opts.Has("allowempty")
+	f.def, f.hasDef = f.opts.Get("default")
+	if sep, ok := f.opts.Get("sep"); ok && sep != "" {
+		f.sep = sep
+	}
+	if f.minItems, err = intOption(f.opts, "minitems"); err != nil {
+		return nil, &TagError{Option: "minitems", Err: err}
+	}
+	if f.maxItems, err = intOption(f.opts, "maxitems"); err != nil {
+		return nil, &TagError{Option: "maxitems", Err: err}
+	}
+	f.layout = time.RFC3339
+	if layout, ok := f.opts.Get("layout"); ok {
+		f.layout = layout
+		if named, ok := namedLayouts[layout]; ok {
+			f.layout = named
+		}
+	}
+	if tz, ok := f.opts.Get("tz"); ok {
+		if f.loc, err = time.LoadLocation(tz); err != nil {
+			return nil, &TagError{Option: "tz", Err: err}
+		}
+	}
+	if err := f.compileSortOptions(sf.Type); err != nil {
+		return nil, err
+	}
+	if err := f.compileFilterOptions(sf.Type); err != nil {
+		return nil, err
+	}
+	if err := f.compileFieldsOptions(sf.Type); err != nil {
+		return nil, err
+	}
+	if f.opts.Has("maxlimit") && indirectType(sf.Type) != paginationType {
+		return nil, &TagError{Option: "maxlimit", Err: fmt.Errorf("only form.Pagination accepts the maxlimit option, got %s", sf.Type)}
+	}
+
+	t := sf.Type
+	switch {
+	case f.source == SourceFile:
+		f.kind = fileField
+		if err := f.compileFileOptions(); err != nil {
+			return nil, err
+		}
+		if !supportsFile(t) {
+			return nil, &TagError{Option: f.source, Err: fmt.Errorf("unsupported type %s", t)}
+		}
+		return f, nil
+	case indirectType(t) == paginationType:
+		return f, f.compilePaginationOptions(t)
+	case isValuesUnmarshaler(t):
+		if !isKeyed(f.source) {
+			return nil, &TagError{Option: f.source, Err: fmt.Errorf("%s cannot be decoded from %s", t, f.source)}
+		}
+		if f.hasDef {
+			return nil, &TagError{Option: "default", Err: fmt.Errorf("%s cannot have a default", t)}
+		}
+		f.kind = valuesField
+		return f, nil
+	case c.isNested(t):
+		if !isKeyed(f.source) {
+			return nil, &TagError{Option: f.source, Err: fmt.Errorf("nested structs cannot be decoded from %s", f.source)}
+		}
+		f.kind = nestedField
+		f.nested, err = c.compile(indirectType(t))
+		return f, err
+	case c.isSlice(t):
+		f.kind = sliceField
+		t = t.Elem()
+	case c.isMap(t):
+		if !isKeyed(f.source) {
+			return nil, &TagError{Option: f.source, Err: fmt.Errorf("maps cannot be decoded from %s", f.source)}
+		}
+		if f.hasDef {
+			return nil, &TagError{Option: "default", Err: errors.New("maps cannot have a default")}
+		}
+		f.kind = mapField
+		if keys, ok := f.opts.Get("keys"); ok {
+			f.keys = strings.Split(keys, ",")
+		}
+		if f.maxKeys, err = intOption(f.opts, "maxkeys"); err != nil {
+			return nil, &TagError{Option: "maxkeys", Err: err}
+		}
+		t = t.Elem()
+	}
+	if !c.convertible(t) {
+		return nil, &TagError{Option: f.source, Err: fmt.Errorf("unsupported type %s", sf.Type)}
+	}
+	if f.hasDef {
+		if err := f.checkDefault(sf.Type, c.config.converters); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// compileFileOptions parses the options of a file tag.
+func (f *field) compileFileOptions() error {
+	if size, ok := f.opts.Get("maxsize"); ok {
+		n, err := units.ParseBase2Bytes(size)
+		if err != nil {
+			return &TagError{Option: "maxsize", Err: err}
+		}
+		f.maxSize = int64(n)
+	}
+	var err error
+	if f.maxFiles, err = intOption(f.opts, "maxfiles"); err != nil {
+		return &TagError{Option: "maxfiles", Err: err}
+	}
+	if types, ok := f.opts.Get("types"); ok {
+		f.types = strings.Split(types, ",")
+	}
+	return nil
+}
+
+// checkDefault checks the default value converts to t with converters.
+func (f *field) checkDefault(t reflect.Type, converters map[reflect.Type]Converter) error {
+	d := &Decoder{converters: converters}
+	if f.kind != sliceField {
+		return d.setDefault(reflect.New(t).Elem(), "", f.def, f)
+	}
+	for _, item := range strings.Split(f.def, f.sep) {
+		if err := d.setDefault(reflect.New(t.Elem()).Elem(), "", item, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertible reports whether a value of type t can be converted from a string.
+func (c *compiler) convertible(t reflect.Type) bool {
+	if c.config.hasConverter(t) {
+		return true
+	}
+	t = indirectType(t)
+	if isUnmarshaler(t) || t == timeType || t == durationType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,371 @@
+package form
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Range operators, given as keys nested into the key of a range, e.g
+// price[gte]=10&price[lt]=100.
+const (
+	OpGreaterThan        = "gt"
+	OpGreaterThanOrEqual = "gte"
+	OpLessThan           = "lt"
+	OpLessThanOrEqual    = "lte"
+)
+
+var rangeOps = []string{OpGreaterThan, OpGreaterThanOrEqual, OpLessThan, OpLessThanOrEqual}
+
+// rangeSeparator separates the bounds of a range, e.g 10..100.
+const rangeSeparator = ".."
+
+var errRangeOrder = message("form.range_order")
+
+// bounds are the raw bounds of a range, an empty bound being open.
+type bounds struct {
+	min, max                   string
+	minExclusive, maxExclusive bool
+}
+
+// parseBounds parses the values of a range: either a single value such as
+// 10..100, 10.. or ..100, a value without separator being both bounds,
+// or the operators nested into its key.
+func parseBounds(values url.Values) (bounds, error) {
+	var b bounds
+	if vals, ok := values[""]; ok {
+		if len(values) > 1 {
+			return b, message("form.range_operators")
+		}
+		val := vals[0]
+		i := strings.Index(val, rangeSeparator)
+		if i < 0 {
+			b.min, b.max = val, val
+		} else {
+			b.min, b.max = val[:i], val[i+len(rangeSeparator):]
+		}
+		if b.min == "" && b.max == "" {
+			return b, message("form.range_syntax")
+		}
+		return b, nil
+	}
+	ops := make([]string, 0, len(values))
+	for op := range values {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		val := values.Get(op)
+		var bound *string
+		switch op {
+		case OpGreaterThan, OpGreaterThanOrEqual:
+			bound = &b.min
+			b.minExclusive = op == OpGreaterThan
+		case OpLessThan, OpLessThanOrEqual:
+			bound = &b.max
+			b.maxExclusive = op == OpLessThan
+		default:
+			return b, message("form.range_operator", "operator", op, "expected", rangeOps)
+		}
+		if *bound != "" {
+			return b, message("form.range_bound")
+		}
+		if val == "" {
+			return b, message("form.range_value", "operator", op)
+		}
+		*bound = val
+	}
+	return b, nil
+}
+
+// values returns the operators of the bounds, see ValuesMarshaler.
+func (b bounds) values() url.Values {
+	values := url.Values{}
+	if b.min != "" {
+		op := OpGreaterThanOrEqual
+		if b.minExclusive {
+			op = OpGreaterThan
+		}
+		values.Set(op, b.min)
+	}
+	if b.max != "" {
+		op := OpLessThanOrEqual
+		if b.maxExclusive {
+			op = OpLessThan
+		}
+		values.Set(op, b.max)
+	}
+	return values
+}
+
+// text returns the bounds in the 10..100 format, which cannot represent
+// exclusive bounds.
+func (b bounds) text() ([]byte, error) {
+	if b.minExclusive || b.maxExclusive {
+		return nil, errors.New("exclusive bounds cannot be marshalled as text")
+	}
+	if b.min == b.max {
+		return []byte(b.min), nil
+	}
+	return []byte(b.min + rangeSeparator + b.max), nil
+}
+
+// checkOrder checks the bounds of a range given their comparison, cmp
+// being negative if min is lower than max.
+func (b bounds) checkOrder(cmp int) error {
+	if cmp > 0 || (cmp == 0 && (b.minExclusive || b.maxExclusive)) {
+		return errRangeOrder
+	}
+	return nil
+}
+
+// IntRange is an interval of integers decoded from 10..100, 10.., ..100 or
+// the gt, gte, lt and lte operators, e.g price[gte]=10&price[lt]=100. A nil
+// bound is open.
+type IntRange struct {
+	Min, Max                   *int64
+	MinExclusive, MaxExclusive bool
+}
+
+// UnmarshalValues implements ValuesUnmarshaler
+func (r *IntRange) UnmarshalValues(values url.Values) error {
+	b, err := parseBounds(values)
+	if err != nil {
+		return err
+	}
+	var v IntRange
+	if v.Min, err = parseIntBound(b.min); err != nil {
+		return err
+	}
+	if v.Max, err = parseIntBound(b.max); err != nil {
+		return err
+	}
+	v.MinExclusive, v.MaxExclusive = b.minExclusive, b.maxExclusive
+	if v.Min != nil && v.Max != nil {
+		cmp := 0
+		if *v.Min < *v.Max {
+			cmp = -1
+		} else if *v.Min > *v.Max {
+			cmp = 1
+		}
+		if err := b.checkOrder(cmp); err != nil {
+			return err
+		}
+	}
+	*r = v
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (r *IntRange) UnmarshalText(text []byte) error {
+	return r.UnmarshalValues(url.Values{"": {string(text)}})
+}
+
+// MarshalValues implements ValuesMarshaler
+func (r IntRange) MarshalValues() (url.Values, error) {
+	return r.bounds().values(), nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r IntRange) MarshalText() ([]byte, error) {
+	return r.bounds().text()
+}
+
+func (r IntRange) bounds() bounds {
+	b := bounds{minExclusive: r.MinExclusive, maxExclusive: r.MaxExclusive}
+	if r.Min != nil {
+		b.min = strconv.FormatInt(*r.Min, 10)
+	}
+	if r.Max != nil {
+		b.max = strconv.FormatInt(*r.Max, 10)
+	}
+	return b
+}
+
+// Contains reports whether v is within r.
+func (r IntRange) Contains(v int64) bool {
+	if r.Min != nil && (v < *r.Min || (r.MinExclusive && v == *r.Min)) {
+		return false
+	}
+	return r.Max == nil || (v < *r.Max || (!r.MaxExclusive && v == *r.Max))
+}
+
+func parseIntBound(s string) (*int64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, errIntSyntax
+	}
+	return &v, nil
+}
+
+// FloatRange is an interval of floats, see IntRange.
+type FloatRange struct {
+	Min, Max                   *float64
+	MinExclusive, MaxExclusive bool
+}
+
+// UnmarshalValues implements ValuesUnmarshaler
+func (r *FloatRange) UnmarshalValues(values url.Values) error {
+	b, err := parseBounds(values)
+	if err != nil {
+		return err
+	}
+	var v FloatRange
+	if v.Min, err = parseFloatBound(b.min); err != nil {
+		return err
+	}
+	if v.Max, err = parseFloatBound(b.max); err != nil {
+		return err
+	}
+	v.MinExclusive, v.MaxExclusive = b.minExclusive, b.maxExclusive
+	if v.Min != nil && v.Max != nil {
+		cmp := 0
+		if *v.Min < *v.Max {
+			cmp = -1
+		} else if *v.Min > *v.Max {
+			cmp = 1
+		}
+		if err := b.checkOrder(cmp); err != nil {
+			return err
+		}
+	}
+	*r = v
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (r *FloatRange) UnmarshalText(text []byte) error {
+	return r.UnmarshalValues(url.Values{"": {string(text)}})
+}
+
+// MarshalValues implements ValuesMarshaler
+func (r FloatRange) MarshalValues() (url.Values, error) {
+	return r.bounds().values(), nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r FloatRange) MarshalText() ([]byte, error) {
+	return r.bounds().text()
+}
+
+func (r FloatRange) bounds() bounds {
+	b := bounds{minExclusive: r.MinExclusive, maxExclusive: r.MaxExclusive}
+	if r.Min != nil {
+		b.min = strconv.FormatFloat(*r.Min, 'f', -1, 64)
+	}
+	if r.Max != nil {
+		b.max = strconv.FormatFloat(*r.Max, 'f', -1, 64)
+	}
+	return b
+}
+
+// Contains reports whether v is within r.
+func (r FloatRange) Contains(v float64) bool {
+	if r.Min != nil && (v < *r.Min || (r.MinExclusive && v == *r.Min)) {
+		return false
+	}
+	return r.Max == nil || (v < *r.Max || (!r.MaxExclusive && v == *r.Max))
+}
+
+func parseFloatBound(s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		// a NaN bound would pass the order check, non-finite bounds are rejected
+		// like non-finite scalars
+		return nil, errFloatSyntax
+	}
+	return &v, nil
+}
+
+// TimeRange is an interval of times, see IntRange. Bounds are given in
+// RFC 3339 or as dates in the 2006-01-02 format, at midnight UTC.
+type TimeRange struct {
+	Min, Max                   *time.Time
+	MinExclusive, MaxExclusive bool
+}
+
+// UnmarshalValues implements ValuesUnmarshaler
+func (r *TimeRange) UnmarshalValues(values url.Values) error {
+	b, err := parseBounds(values)
+	if err != nil {
+		return err
+	}
+	var v TimeRange
+	if v.Min, err = parseTimeBound(b.min); err != nil {
+		return err
+	}
+	if v.Max, err = parseTimeBound(b.max); err != nil {
+		return err
+	}
+	v.MinExclusive, v.MaxExclusive = b.minExclusive, b.maxExclusive
+	if v.Min != nil && v.Max != nil {
+		cmp := 0
+		if v.Min.Before(*v.Max) {
+			cmp = -1
+		} else if v.Min.After(*v.Max) {
+			cmp = 1
+		}
+		if err := b.checkOrder(cmp); err != nil {
+			return err
+		}
+	}
+	*r = v
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (r *TimeRange) UnmarshalText(text []byte) error {
+	return r.UnmarshalValues(url.Values{"": {string(text)}})
+}
+
+// MarshalValues implements ValuesMarshaler
+func (r TimeRange) MarshalValues() (url.Values, error) {
+	return r.bounds().values(), nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r TimeRange) MarshalText() ([]byte, error) {
+	return r.bounds().text()
+}
+
+func (r TimeRange) bounds() bounds {
+	b := bounds{minExclusive: r.MinExclusive, maxExclusive: r.MaxExclusive}
+	if r.Min != nil {
+		b.min = r.Min.Format(time.RFC3339Nano)
+	}
+	if r.Max != nil {
+		b.max = r.Max.Format(time.RFC3339Nano)
+	}
+	return b
+}
+
+// Contains reports whether t is within r.
+func (r TimeRange) Contains(t time.Time) bool {
+	if r.Min != nil && (t.Before(*r.Min) || (r.MinExclusive && t.Equal(*r.Min))) {
+		return false
+	}
+	return r.Max == nil || (t.Before(*r.Max) || (!r.MaxExclusive && t.Equal(*r.Max)))
+}
+
+func parseTimeBound(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", s); err != nil {
+			return nil, message("form.range_time")
+		}
+	}
+	return &t, nil
+}
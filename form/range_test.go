@@ -0,0 +1,120 @@
+package form_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sganon/go-request/form"
+	"github.com/sganon/go-request/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func TestIntRange(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   form.IntRange
+		err    string
+		code   string
+		id     string
+		params map[string]interface{}
+	}{
+		{query: "price=10..100", want: form.IntRange{Min: int64Ptr(10), Max: int64Ptr(100)}},
+		{query: "price=10..", want: form.IntRange{Min: int64Ptr(10)}},
+		{query: "price=..-5", want: form.IntRange{Max: int64Ptr(-5)}},
+		{query: "price=42", want: form.IntRange{Min: int64Ptr(42), Max: int64Ptr(42)}},
+		{query: "price[gte]=10&price[lt]=100", want: form.IntRange{Min: int64Ptr(10), Max: int64Ptr(100), MaxExclusive: true}},
+		{query: "price.gt=10", want: form.IntRange{Min: int64Ptr(10), MinExclusive: true}},
+		{query: "price=100..10", err: "min must be at most max", code: "invalid_value", id: "form.range_order"},
+		{query: "price[gt]=10&price[lt]=10", err: "min must be at most max", code: "invalid_value", id: "form.range_order"},
+		{query: "price=..", err: "syntax error: expected a range such as 10..100, 10.. or ..100", code: "invalid_format", id: "form.range_syntax"},
+		{query: "price=a..b", err: "syntax error: unable to convert to integer", code: "type_mismatch", id: "form.int_syntax", params: map[string]interface{}{"expected_type": "integer"}},
+		{query: "price[eq]=10", err: `unknown operator "eq", expected one of: gt, gte, lt, lte`, code: "invalid_value", id: "form.range_operator", params: map[string]interface{}{"operator": "eq", "expected": []string{"gt", "gte", "lt", "lte"}}},
+		{query: "price[gt]=1&price[gte]=2", err: "a bound cannot be given twice", code: "duplicate", id: "form.range_bound"},
+		{query: "price=1..2&price[lt]=3", err: "a range cannot be given with operators", code: "conflict", id: "form.range_operators"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var output struct {
+				Price form.IntRange `form:"price"`
+			}
+			decoder := form.NewDecoder(httptest.NewRequest("GET", "/?"+tt.query, nil))
+			decoder.Decode(&output)
+			if tt.err != "" {
+				if assert.NotNil(t, decoder.Input) {
+					assert.Equal(t, []problem.ParamError{{Field: "price", Reason: tt.err, In: "form", Code: tt.code, MessageID: tt.id, Params: tt.params}}, decoder.Input.InvalidParams)
+				}
+				return
+			}
+			assert.Nil(t, decoder.Input)
+			assert.Equal(t, tt.want, output.Price)
+		})
+	}
+
+	r := form.IntRange{Min: int64Ptr(10), Max: int64Ptr(100), MaxExclusive: true}
+	assert.True(t, r.Contains(10))
+	assert.False(t, r.Contains(100))
+	assert.False(t, r.Contains(9))
+	values, err := r.MarshalValues()
+	assert.NoError(t, err)
+	assert.Equal(t, url.Values{"gte": {"10"}, "lt": {"100"}}, values)
+}
+
+func TestRangeFields(t *testing.T) {
+	type input struct {
+		Price   *form.FloatRange `form:"price"`
+		Created form.TimeRange   `form:"created,required"`
+		Sizes   []form.IntRange  `form:"sizes"`
+	}
+	req := httptest.NewRequest("GET", "/?price[gt]=1.5&created=2020-01-01..2020-02-01T12:00:00Z&sizes=1..2,3..", nil)
+	var output input
+	decoder := form.NewDecoder(req)
+	decoder.Decode(&output)
+	assert.Nil(t, decoder.Input)
+	if assert.NotNil(t, output.Price) {
+		assert.Equal(t, 1.5, *output.Price.Min)
+		assert.True(t, output.Price.MinExclusive)
+		assert.Nil(t, output.Price.Max)
+	}
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), *output.Created.Min)
+	assert.Equal(t, time.Date(2020, 2, 1, 12, 0, 0, 0, time.UTC), *output.Created.Max)
+	assert.True(t, output.Created.Contains(time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC)))
+	if assert.Len(t, output.Sizes, 2) {
+		assert.Equal(t, int64(3), *output.Sizes[1].Min)
+	}
+	assert.True(t, decoder.Provided("price"))
+
+	values, err := form.NewEncoder().Encode(output)
+	assert.NoError(t, err)
+	assert.Equal(t, "created.gte=2020-01-01T00%3A00%3A00Z&created.lte=2020-02-01T12%3A00%3A00Z&price.gt=1.5&sizes=1..2&sizes=3..", values.Encode())
+
+	req = httptest.NewRequest("GET", "/?price=2..1", nil)
+	output = input{}
+	decoder = form.NewDecoder(req)
+	decoder.Decode(&output)
+	if assert.NotNil(t, decoder.Input) {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "price", Reason: "min must be at most max", In: "form", Code: "invalid_value", MessageID: "form.range_order"},
+			{Field: "created", Reason: "parameter is required", In: "form", Code: "required", MessageID: "form.required"},
+		}, decoder.Input.InvalidParams)
+	}
+	assert.Nil(t, output.Price)
+
+	for _, query := range []string{"price=NaN..1", "price[lt]=NaN", "price=..Inf"} {
+		req = httptest.NewRequest("GET", "/?created=2020-01-01&"+query, nil)
+		output = input{}
+		decoder = form.NewDecoder(req)
+		decoder.Decode(&output)
+		if assert.NotNil(t, decoder.Input, query) {
+			assert.Equal(t, []problem.ParamError{
+				{Field: "price", Reason: "syntax error: unable to convert to float", In: "form", Code: "type_mismatch", MessageID: "form.float_syntax", Params: map[string]interface{}{"expected_type": "float"}},
+			}, decoder.Input.InvalidParams, query)
+		}
+		assert.Nil(t, output.Price, query)
+	}
+}
@@ -0,0 +1,100 @@
+package form
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// extractSlice decodes the values of key onto the slice e. Values can be given
+// through repeated keys (?id=1&id=2), the bracket notation (?id[]=1&id[]=2)
+// or a single value split with the sep option, which defaults to a comma.
+func (d *Decoder) extractSlice(key string, f *field, e reflect.Value) error {
+	vals, present := d.lookup(f.source, key)
+	if present {
+		d.markProvided(key)
+	}
+	var items []string
+	for _, val := range vals {
+		if val == "" {
+			continue
+		}
+		items = append(items, strings.Split(val, f.sep)...)
+	}
+	if f.hasDef && len(items) == 0 {
+		items := strings.Split(f.def, f.sep)
+		slice := reflect.MakeSlice(e.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := d.setDefault(slice.Index(i), key, item, f); err != nil {
+				return err
+			}
+		}
+		e.Set(slice)
+		return nil
+	}
+	if len(items) == 0 {
+		if f.required {
+			d.addParamsReason(key, f.source, errRequired)
+		}
+		return nil
+	}
+
+	slice := reflect.MakeSlice(e.Type(), len(items), len(items))
+	valid := true
+	for i, item := range items {
+		ok, err := d.setFromType(slice.Index(i), fmt.Sprintf("%s[%d]", key, i), item, f)
+		if err != nil {
+			return err
+		}
+		valid = valid && ok
+	}
+	if !valid {
+		return nil
+	}
+
+	if f.minItems > 0 && len(items) < f.minItems {
+		d.addParamsReason(key, f.source, message("form.min_items", "min", f.minItems, "count", len(items)))
+		return nil
+	}
+	if f.maxItems > 0 && len(items) > f.maxItems {
+		d.addParamsReason(key, f.source, message("form.max_items", "max", f.maxItems, "count", len(items)))
+		return nil
+	}
+	if f.opts.Has("unique") {
+		if i, ok := firstDuplicate(slice, items); ok {
+			d.addParamsReason(fmt.Sprintf("%s[%d]", key, i), f.source, message("form.duplicate", "value", items[i]))
+			return nil
+		}
+	}
+	e.Set(slice)
+	return d.checkRules(key, f, e)
+}
+
+// firstDuplicate returns the index of the first element of slice already seen.
+// Decoded values are compared when possible so that 1 and 01 are duplicates,
+// pointers being dereferenced, raw items are compared otherwise.
+func firstDuplicate(slice reflect.Value, items []string) (int, bool) {
+	comparable := indirectType(slice.Type().Elem()).Comparable()
+	seen := make(map[interface{}]struct{}, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		var v interface{} = items[i]
+		if elem := reflect.Indirect(slice.Index(i)); comparable && elem.IsValid() {
+			v = elem.Interface()
+		}
+		if _, ok := seen[v]; ok {
+			return i, true
+		}
+		seen[v] = struct{}{}
+	}
+	return 0, false
+}
+
+// intOption returns the integer value of the option name, 0 if not set.
+func intOption(opts tagOptions, name string) (int, error) {
+	v, ok := opts.Get(name)
+	if !ok {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
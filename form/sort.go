@@ -0,0 +1,132 @@
+package form
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var sortType = reflect.TypeOf(Sort{})
+
+// SortField is a field of a Sort and its direction.
+type SortField struct {
+	Name string
+	Desc bool
+}
+
+// Sort is an ordered list of fields decoded from ?sort=-created_at,name, a
+// field prefixed with - being sorted in descending order. The sortable option
+// lists the fields which can be sorted, e.g `form:"sort,sortable=created_at,name"`.
+type Sort []SortField
+
+// ParseSort parses a comma separated list of fields, see Sort.
+func ParseSort(s string) (Sort, error) {
+	if s == "" {
+		return nil, nil
+	}
+	items := strings.Split(s, ",")
+	sort := make(Sort, 0, len(items))
+	for _, item := range items {
+		field := SortField{Name: strings.TrimSpace(item)}
+		switch {
+		case strings.HasPrefix(field.Name, "-"):
+			field.Name, field.Desc = field.Name[1:], true
+		case strings.HasPrefix(field.Name, "+"):
+			field.Name = field.Name[1:]
+		}
+		if field.Name == "" {
+			return nil, message("form.sort_syntax")
+		}
+		if sort.Has(field.Name) {
+			return nil, message("form.sort_twice", "field", field.Name)
+		}
+		sort = append(sort, field)
+	}
+	return sort, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *Sort) UnmarshalText(text []byte) error {
+	sort, err := ParseSort(string(text))
+	if err != nil {
+		return err
+	}
+	*s = sort
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Sort) MarshalText() ([]byte, error) {
+	items := make([]string, len(s))
+	for i, field := range s {
+		items[i] = field.Name
+		if field.Desc {
+			items[i] = "-" + field.Name
+		}
+	}
+	return []byte(strings.Join(items, ",")), nil
+}
+
+// Has reports whether the field name is sorted.
+func (s Sort) Has(name string) bool {
+	for _, field := range s {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderBy returns the ORDER BY clause of s without its keyword, e.g
+// "p.created_at DESC, p.name ASC". Columns maps each sortable field to its
+// SQL expression: the clause is only made of these expressions and never of
+// the request values, a field missing from columns being an error.
+func (s Sort) OrderBy(columns map[string]string) (string, error) {
+	items := make([]string, len(s))
+	for i, field := range s {
+		column, ok := columns[field.Name]
+		if !ok {
+			return "", fmt.Errorf("form: no column to sort by %q", field.Name)
+		}
+		dir := "ASC"
+		if field.Desc {
+			dir = "DESC"
+		}
+		items[i] = column + " " + dir
+	}
+	return strings.Join(items, ", "), nil
+}
+
+// convertSort converts val if e is a Sort, checking its fields against the
+// sortable option, it reports whether e is a Sort.
+func convertSort(e reflect.Value, val string, f *field) (bool, error) {
+	if e.Type() != sortType {
+		return false, nil
+	}
+	sort, err := ParseSort(val)
+	if err != nil {
+		return true, err
+	}
+	if f.sortable != nil {
+		for _, field := range sort {
+			if !contains(f.sortable, field.Name) {
+				return true, message("form.sort_field", "field", field.Name, "expected", f.sortable)
+			}
+		}
+	}
+	e.Set(reflect.ValueOf(sort))
+	return true, nil
+}
+
+// compileSortOptions parses the sortable option, only accepted by Sort fields.
+func (f *field) compileSortOptions(t reflect.Type) error {
+	sortable, ok := f.opts.Get("sortable")
+	if !ok {
+		return nil
+	}
+	if indirectType(t) != sortType {
+		return &TagError{Option: "sortable", Err: fmt.Errorf("only form.Sort accepts the sortable option, got %s", t)}
+	}
+	f.sortable = strings.Split(sortable, ",")
+	return nil
+}
@@ -0,0 +1,65 @@
+package form_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sganon/go-request/form"
+	"github.com/sganon/go-request/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+type sortInput struct {
+	Sort form.Sort `query:"sort,sortable=created_at,name,price,default=-created_at"`
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   form.Sort
+		err    string
+		code   string
+		id     string
+		params map[string]interface{}
+	}{
+		{query: "sort=-created_at,name", want: form.Sort{{Name: "created_at", Desc: true}, {Name: "name"}}},
+		{query: "sort=+price", want: form.Sort{{Name: "price"}}},
+		{query: "", want: form.Sort{{Name: "created_at", Desc: true}}},
+		{query: "sort=name,-password", err: `cannot sort by "password", expected one of: created_at, name, price`, code: "invalid_value", id: "form.sort_field", params: map[string]interface{}{"field": "password", "expected": []string{"created_at", "name", "price"}}},
+		{query: "sort=name,", err: "syntax error: expected fields such as -created_at,name", code: "invalid_format", id: "form.sort_syntax"},
+		{query: "sort=name,-name", err: `field "name" cannot be sorted twice`, code: "duplicate", id: "form.sort_twice", params: map[string]interface{}{"field": "name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var output sortInput
+			decoder := form.NewDecoder(httptest.NewRequest("GET", "/?"+tt.query, nil))
+			decoder.Decode(&output)
+			if tt.err != "" {
+				if assert.NotNil(t, decoder.Input) {
+					assert.Equal(t, []problem.ParamError{{Field: "sort", Reason: tt.err, In: "query", Code: tt.code, MessageID: tt.id, Params: tt.params}}, decoder.Input.InvalidParams)
+				}
+				return
+			}
+			assert.Nil(t, decoder.Input)
+			assert.Equal(t, tt.want, output.Sort)
+		})
+	}
+
+	s := form.Sort{{Name: "created_at", Desc: true}, {Name: "name"}}
+	orderBy, err := s.OrderBy(map[string]string{"created_at": "p.created_at", "name": "lower(p.name)"})
+	assert.NoError(t, err)
+	assert.Equal(t, "p.created_at DESC, lower(p.name) ASC", orderBy)
+	_, err = s.OrderBy(map[string]string{"name": "p.name"})
+	assert.EqualError(t, err, `form: no column to sort by "created_at"`)
+
+	values, err := form.NewEncoder().Encode(sortInput{Sort: s})
+	assert.NoError(t, err)
+	assert.Equal(t, "sort=-created_at%2Cname", values.Encode())
+
+	assert.Error(t, form.Compile(struct {
+		Sort form.Sort `query:"sort,sortable=name,default=price"`
+	}{}), "the default should be sortable")
+	assert.Error(t, form.Compile(struct {
+		Name string `query:"name,sortable=name"`
+	}{}), "only form.Sort accepts the sortable option")
+}
@@ -0,0 +1,207 @@
+package form
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Sources of the parameters, each one being read from the field tag of the
+// same name. Sources are also used as the In of parameter errors.
+const (
+	// SourceForm reads the URL query merged with the url-encoded or multipart
+	// body, as http.Request.FormValue does.
+	SourceForm = "form"
+	// SourceQuery only reads the URL query.
+	SourceQuery = "query"
+	// SourcePostForm only reads the url-encoded or multipart body.
+	SourcePostForm = "postform"
+	// SourceHeader reads the request headers.
+	SourceHeader = "header"
+	// SourceCookie reads the request cookies.
+	SourceCookie = "cookie"
+	// SourcePath reads the path parameters given by the PathParamSource
+	// of the decoder.
+	SourcePath = "path"
+	// SourceFile reads the files of a multipart body.
+	SourceFile = "file"
+)
+
+// sources lists the field tags in the order they are looked up.
+var sources = []string{SourceForm, SourceQuery, SourcePostForm, SourceHeader, SourceCookie, SourcePath, SourceFile}
+
+// isKeyed reports whether the keys of source can be nested, that is whether
+// nested structs can be decoded from it.
+func isKeyed(source string) bool {
+	return source == SourceForm || source == SourceQuery || source == SourcePostForm
+}
+
+// lookup returns the values of key in source and whether the key is present,
+// the key given in the request being looked up if key has an alias.
+func (d *Decoder) lookup(source, key string) ([]string, bool) {
+	key = d.requestKey(source, key)
+	if isKeyed(source) {
+		d.markLookup(source, key)
+	}
+	return d.lookupKey(source, key)
+}
+
+// lookupKey returns the values of key in source and whether the key is present.
+func (d *Decoder) lookupKey(source, key string) ([]string, bool) {
+	switch source {
+	case SourceHeader:
+		vals, ok := d.r.Header[http.CanonicalHeaderKey(key)]
+		return vals, ok
+	case SourceCookie:
+		var vals []string
+		for _, cookie := range d.r.Cookies() {
+			if cookie.Name == key {
+				vals = append(vals, cookie.Value)
+			}
+		}
+		return vals, vals != nil
+	case SourcePath:
+		source := d.PathParams
+		if source == nil {
+			source = ServeMuxParams{}
+		}
+		v, ok := source.PathParam(d.r, key)
+		if !ok {
+			return nil, false
+		}
+		return []string{v}, true
+	}
+	vals, ok := d.keyed(source)[key]
+	return vals, ok
+}
+
+// keyed returns the values of a keyed source with their keys normalized, see normalizeKey.
+func (d *Decoder) keyed(source string) url.Values {
+	if d.values == nil {
+		d.values = make(map[string]url.Values)
+	}
+	if values, ok := d.values[source]; ok {
+		return values
+	}
+	if d.r.Form == nil {
+		// Same behaviour as http.Request.FormValue, errors are ignored
+		d.r.ParseMultipartForm(d.maxMemory())
+	}
+	raw := d.r.Form
+	switch source {
+	case SourceQuery:
+		raw = d.r.URL.Query()
+	case SourcePostForm:
+		raw = d.r.PostForm
+	}
+	values := make(url.Values, len(raw))
+	for key, vals := range raw {
+		key = normalizeKey(key)
+		values[key] = append(values[key], vals...)
+	}
+	d.values[source] = values
+	return values
+}
+
+// hasPrefix reports whether a key nested into prefix is given in source,
+// the prefix given in the request being looked up if prefix has an alias.
+func (d *Decoder) hasPrefix(source, prefix string) bool {
+	return d.hasKeyPrefix(source, d.requestKey(source, prefix))
+}
+
+// hasKeyPrefix reports whether a key nested into prefix is given in source.
+func (d *Decoder) hasKeyPrefix(source, prefix string) bool {
+	prefix += "."
+	for key := range d.keyed(source) {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// aliasKey is the key of Decoder.aliased, keyed sources sharing their keys.
+type aliasKey struct {
+	source, key string
+}
+
+func newAliasKey(source, key string) aliasKey {
+	if isKeyed(source) {
+		source = SourceForm
+	}
+	return aliasKey{source, key}
+}
+
+// resolveAlias records the key given in the request for the key of f nested
+// into prefix: the first of its name and aliases which is given, within the
+// prefix given in the request. Errors and Provided use the key of the name.
+func (d *Decoder) resolveAlias(prefix, key string, f *field) {
+	keyed := isKeyed(f.source)
+	requestPrefix := prefix
+	if keyed {
+		requestPrefix = d.requestKey(f.source, prefix)
+	}
+	if len(f.aliases) == 0 && requestPrefix == prefix {
+		return
+	}
+	requestKey := ""
+	for i := -1; i < len(f.aliases) && requestKey == ""; i++ {
+		name := f.name
+		if i >= 0 {
+			name = f.aliases[i]
+		}
+		if keyed {
+			name = joinKey(requestPrefix, name)
+		}
+		if d.given(f, name) {
+			requestKey = name
+		}
+	}
+	if requestKey == "" {
+		requestKey = f.name
+		if keyed {
+			requestKey = joinKey(requestPrefix, f.name)
+		}
+	}
+	if requestKey == key {
+		return
+	}
+	if d.aliased == nil {
+		d.aliased = make(map[aliasKey]string)
+	}
+	d.aliased[newAliasKey(f.source, key)] = requestKey
+}
+
+// given reports whether the key of f is given in the request.
+func (d *Decoder) given(f *field, key string) bool {
+	switch f.kind {
+	case nestedField, mapField:
+		return d.hasKeyPrefix(f.source, key)
+	case valuesField:
+		_, ok := d.lookupKey(f.source, key)
+		return ok || d.hasKeyPrefix(f.source, key)
+	case paginationField:
+		for _, name := range []string{PageParam, LimitParam, CursorParam} {
+			if _, ok := d.lookupKey(f.source, joinKey(key, name)); ok {
+				return true
+			}
+		}
+		return false
+	case fileField:
+		headers, _ := d.fileHeaders(key)
+		return len(headers) > 0
+	}
+	_, ok := d.lookupKey(f.source, key)
+	return ok
+}
+
+// requestKey returns the key given in the request for key, see resolveAlias.
+func (d *Decoder) requestKey(source, key string) string {
+	if len(d.aliased) == 0 {
+		return key
+	}
+	if requestKey, ok := d.aliased[newAliasKey(source, key)]; ok {
+		return requestKey
+	}
+	return key
+}
@@ -0,0 +1,122 @@
+package form
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// maxSuggestionDistance is the maximum edit distance between an unknown key
+// and the declared key suggested in its place.
+const maxSuggestionDistance = 2
+
+// markLookup records that key was looked up in the keyed source, that is
+// declared by the decoded struct.
+func (d *Decoder) markLookup(source, key string) {
+	if d.lookups == nil {
+		d.lookups = make(map[string]map[string]bool)
+	}
+	if d.lookups[source] == nil {
+		d.lookups[source] = make(map[string]bool)
+	}
+	d.lookups[source][key] = true
+}
+
+// declared reports whether key was looked up in source or in one of the
+// sources including it.
+func (d *Decoder) declared(source, key string) bool {
+	return d.lookups[source][key] || d.lookups[SourceForm][key]
+}
+
+// checkUnknownParams adds an error for each key of the query and of the body
+// which is neither declared by the decoded struct nor allowed.
+func (d *Decoder) checkUnknownParams() {
+	if d.r.Form == nil {
+		d.r.ParseMultipartForm(d.maxMemory())
+	}
+	d.checkUnknownKeys(SourceQuery, d.r.URL.Query())
+	d.checkUnknownKeys(SourcePostForm, d.r.PostForm)
+}
+
+func (d *Decoder) checkUnknownKeys(source string, values url.Values) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if d.declared(source, normalizeKey(key)) || d.allowed(key) {
+			continue
+		}
+		reason := message("form.unknown")
+		if suggestion, ok := d.suggest(source, normalizeKey(key)); ok {
+			reason = message("form.unknown_suggested", "suggestion", suggestion)
+		}
+		d.addParamsReason(key, source, reason)
+	}
+}
+
+// allowed reports whether key matches one of the AllowedParams, which can
+// end with a * to match a prefix.
+func (d *Decoder) allowed(key string) bool {
+	for _, allowed := range d.AllowedParams {
+		if prefix := strings.TrimSuffix(allowed, "*"); prefix != allowed {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest returns the declared key of source closest to key, if close enough.
+func (d *Decoder) suggest(source, key string) (string, bool) {
+	best, bestDist := "", maxSuggestionDistance+1
+	for _, s := range []string{source, SourceForm} {
+		for declared := range d.lookups[s] {
+			dist := distance(key, declared)
+			if dist < bestDist || (dist == bestDist && declared < best) {
+				best, bestDist = declared, dist
+			}
+		}
+	}
+	// A suggestion replacing most of a short key would not make sense
+	if bestDist > maxSuggestionDistance || bestDist >= len(key) {
+		return "", false
+	}
+	return best, true
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,220 @@
+package form
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Types with a builtin time conversion
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+	dateType      = reflect.TypeOf(Date{})
+	timeOfDayType = reflect.TypeOf(TimeOfDay{})
+)
+
+// Special layouts of the layout option, others being used as is by time.Parse.
+const (
+	LayoutUnix      = "unix"
+	LayoutUnixMilli = "unixmilli"
+)
+
+// namedLayouts can be used by their name in the layout option, e.g `form:"from,layout=RFC1123"`
+var namedLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+}
+
+// convertTime converts val if e is one of the time types, it reports whether
+// it handled the conversion.
+func (d *Decoder) convertTime(e reflect.Value, val string, f *field) (bool, error) {
+	switch e.Type() {
+	case timeType:
+		t, err := d.parseTime(val, f)
+		if err != nil {
+			return true, err
+		}
+		e.Set(reflect.ValueOf(t))
+	case durationType:
+		v, err := time.ParseDuration(val)
+		if err != nil {
+			return true, message("form.duration_syntax")
+		}
+		e.SetInt(int64(v))
+	case dateType:
+		var v Date
+		if err := v.UnmarshalText([]byte(val)); err != nil {
+			return true, err
+		}
+		e.Set(reflect.ValueOf(v))
+	case timeOfDayType:
+		var v TimeOfDay
+		if err := v.UnmarshalText([]byte(val)); err != nil {
+			return true, err
+		}
+		e.Set(reflect.ValueOf(v))
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// parseTime parses val with the layout option of f, defaulting to RFC 3339.
+func (d *Decoder) parseTime(val string, f *field) (time.Time, error) {
+	loc := d.location(f)
+	switch f.layout {
+	case LayoutUnix:
+		sec, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return time.Time{}, message("form.unix_syntax")
+		}
+		return time.Unix(sec, 0).In(loc), nil
+	case LayoutUnixMilli:
+		ms, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return time.Time{}, message("form.unix_milli")
+		}
+		return time.Unix(ms/1e3, ms%1e3*1e6).In(loc), nil
+	}
+	t, err := time.ParseInLocation(f.layout, val, loc)
+	if err != nil {
+		return time.Time{}, message("form.time_syntax", "layout", f.layout)
+	}
+	return t, nil
+}
+
+// location returns the location of a time field: the one of its tz option,
+// the one given in the decoder LocationHeader or the decoder Location.
+func (d *Decoder) location(f *field) *time.Location {
+	if f.loc != nil {
+		return f.loc
+	}
+	if loc := d.requestLocation(); loc != nil {
+		return loc
+	}
+	if d.Location != nil {
+		return d.Location
+	}
+	return time.UTC
+}
+
+// requestLocation returns the location given in the LocationHeader, an
+// unknown one being reported once as a parameter error.
+func (d *Decoder) requestLocation() *time.Location {
+	if d.headerLocOK || d.LocationHeader == "" {
+		return d.headerLoc
+	}
+	d.headerLocOK = true
+	tz := d.r.Header.Get(d.LocationHeader)
+	if tz == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		d.addParamsReason(d.LocationHeader, SourceHeader, message("form.time_zone", "zone", tz))
+		return nil
+	}
+	d.headerLoc = loc
+	return loc
+}
+
+// Date is a calendar date without time nor location, decoded from the
+// 2006-01-02 format.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Date) UnmarshalText(text []byte) error {
+	t, err := time.Parse("2006-01-02", string(text))
+	if err != nil {
+		return message("form.date_syntax")
+	}
+	*d = DateOf(t)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler, the zero Date giving an
+// empty text so that it decodes back to the zero Date.
+func (d Date) MarshalText() ([]byte, error) {
+	if d.IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(d.String()), nil
+}
+
+// String returns the date in the 2006-01-02 format.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// IsZero reports whether d is the zero Date.
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// In returns the time at midnight of d in loc.
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// DateOf returns the date of t in its location.
+func DateOf(t time.Time) Date {
+	y, m, day := t.Date()
+	return Date{Year: y, Month: m, Day: day}
+}
+
+// TimeOfDay is a time within a day without date nor location, decoded
+// from the 15:04 or 15:04:05 formats, with optional fractional seconds.
+type TimeOfDay struct {
+	Hour       int
+	Minute     int
+	Second     int
+	Nanosecond int
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (t *TimeOfDay) UnmarshalText(text []byte) error {
+	s := string(text)
+	layout := "15:04:05.999999999"
+	if len(s) == len("15:04") {
+		layout = "15:04"
+	}
+	v, err := time.Parse(layout, s)
+	if err != nil {
+		return message("form.clock_syntax")
+	}
+	*t = TimeOfDay{Hour: v.Hour(), Minute: v.Minute(), Second: v.Second(), Nanosecond: v.Nanosecond()}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (t TimeOfDay) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// String returns the time of day in the 15:04:05 format, followed by the
+// fractional seconds if any.
+func (t TimeOfDay) String() string {
+	s := fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+	if t.Nanosecond != 0 {
+		s += strconv.FormatFloat(float64(t.Nanosecond)/1e9, 'f', -1, 64)[1:]
+	}
+	return s
+}
+
+// On returns the time of t at date in loc.
+func (t TimeOfDay) On(date Date, loc *time.Location) time.Time {
+	return time.Date(date.Year, date.Month, date.Day, t.Hour, t.Minute, t.Second, t.Nanosecond, loc)
+}
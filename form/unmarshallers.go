@@ -38,6 +38,15 @@ func (l *IntList) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// MarshalText implements encoding.TextMarshaler
+func (l IntList) MarshalText() ([]byte, error) {
+	items := make([]string, len(l))
+	for i, v := range l {
+		items[i] = strconv.Itoa(v)
+	}
+	return []byte(strings.Join(items, ",")), nil
+}
+
 // StringList implements encoding.TextUnmarshaler in order to extract query
 // of form ?key=foo,bar onto a string slice
 type StringList []string
@@ -47,3 +56,8 @@ func (l *StringList) UnmarshalText(text []byte) error {
 	*l = StringList(strings.Split(string(text), ","))
 	return nil
 }
+
+// MarshalText implements encoding.TextMarshaler
+func (l StringList) MarshalText() ([]byte, error) {
+	return []byte(strings.Join(l, ",")), nil
+}
@@ -0,0 +1,80 @@
+package form
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// ValuesUnmarshaler is implemented by types decoded from several keys, such
+// as IntRange from price[gte]=10&price[lt]=100. The values of the key of the
+// field are given under the empty key, those of the keys nested into it
+// under their suffix, e.g gte and lt. The message of the returned error is
+// used as the reason of the parameter error.
+type ValuesUnmarshaler interface {
+	UnmarshalValues(values url.Values) error
+}
+
+// ValuesMarshaler is the encoding counterpart of ValuesUnmarshaler, used by
+// Encoder.
+type ValuesMarshaler interface {
+	MarshalValues() (url.Values, error)
+}
+
+// Types implementing ValuesUnmarshaler and ValuesMarshaler
+var (
+	ValuesUnmarshalerType = reflect.TypeOf(new(ValuesUnmarshaler)).Elem()
+	ValuesMarshalerType   = reflect.TypeOf(new(ValuesMarshaler)).Elem()
+)
+
+// fieldChecker is implemented by the ValuesUnmarshalers checked against the
+// options of their field once decoded, such as Fields against its resource.
+type fieldChecker interface {
+	checkField(f *field) error
+}
+
+// isValuesUnmarshaler reports whether values of type t, or of the type it
+// points to, unmarshal themselves from several keys.
+func isValuesUnmarshaler(t reflect.Type) bool {
+	return reflect.PtrTo(indirectType(t)).Implements(ValuesUnmarshalerType)
+}
+
+// extractValues decodes e from the values of key and of the keys nested into it.
+func (d *Decoder) extractValues(key string, f *field, e reflect.Value) error {
+	requestKey := d.requestKey(f.source, key)
+	prefix := requestKey + "."
+	values := url.Values{}
+	for k, vals := range d.keyed(f.source) {
+		switch {
+		case k == requestKey:
+			values[""] = vals
+		case strings.HasPrefix(k, prefix):
+			values[k[len(prefix):]] = vals
+		default:
+			continue
+		}
+		d.markLookup(f.source, k)
+	}
+	if len(values) == 0 {
+		if f.required {
+			d.addParamsReason(key, f.source, errRequired)
+		}
+		return nil
+	}
+	d.markProvided(key)
+	v := reflect.New(indirectType(e.Type()))
+	err := v.Interface().(ValuesUnmarshaler).UnmarshalValues(values)
+	if checker, ok := v.Interface().(fieldChecker); ok && err == nil {
+		err = checker.checkField(f)
+	}
+	if err != nil {
+		d.addParamsReason(key, f.source, err)
+		return nil
+	}
+	if e.Kind() == reflect.Ptr {
+		e.Set(v)
+	} else {
+		e.Set(v.Elem())
+	}
+	return d.checkRules(key, f, e)
+}
@@ -0,0 +1,32 @@
+package problem
+
+// Codes of the parameter errors, see ParamError.
+const (
+	// CodeRequired reports a missing parameter.
+	CodeRequired = "required"
+	// CodeUnknownParameter reports a parameter which is not expected.
+	CodeUnknownParameter = "unknown_parameter"
+	// CodeTypeMismatch reports a value which cannot be converted to the
+	// expected_type param.
+	CodeTypeMismatch = "type_mismatch"
+	// CodeInvalidFormat reports a value which does not follow its format,
+	// such as a date or a sort expression.
+	CodeInvalidFormat = "invalid_format"
+	// CodePattern reports a value which does not match the pattern param.
+	CodePattern = "pattern"
+	// CodeOutOfRange reports a value outside of its min or max params.
+	CodeOutOfRange = "out_of_range"
+	// CodeLength reports a length, or a count of items, outside of its min
+	// or max params.
+	CodeLength = "length"
+	// CodeInvalidValue reports a value which is not one of the expected
+	// param.
+	CodeInvalidValue = "invalid_value"
+	// CodeDuplicate reports a value given more than once.
+	CodeDuplicate = "duplicate"
+	// CodeConflict reports parameters which cannot be given together.
+	CodeConflict = "conflict"
+	// CodeInvalid reports any other invalid value, its reason giving the
+	// details.
+	CodeInvalid = "invalid"
+)
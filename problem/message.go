@@ -0,0 +1,167 @@
+package problem
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is the language of the built-in messages, used when no
+// registered language matches the request.
+const DefaultLanguage = "en"
+
+var (
+	catalogMu sync.RWMutex
+	// catalog holds the templates of the messages by language then by ID,
+	// languages being lowercased.
+	catalog = map[string]map[string]string{}
+	// languages maps the lowercased languages to their registered tag.
+	languages = map[string]string{}
+)
+
+// RegisterMessages adds the templates of messages by ID for lang, a language
+// tag such as fr or pt-BR, replacing the existing ones with the same ID.
+// Templates reference the params of their message by name, e.g
+// "must be at least {min}".
+func RegisterMessages(lang string, templates map[string]string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	key := strings.ToLower(lang)
+	if catalog[key] == nil {
+		catalog[key] = make(map[string]string, len(templates))
+		languages[key] = lang
+	}
+	for id, template := range templates {
+		catalog[key][id] = template
+	}
+}
+
+// template returns the template of the message id in lang, falling back to
+// DefaultLanguage.
+func template(lang, id string) (string, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	if template, ok := catalog[strings.ToLower(lang)][id]; ok {
+		return template, true
+	}
+	template, ok := catalog[DefaultLanguage][id]
+	return template, ok
+}
+
+// Format returns the message id in lang, formatted with params. It falls back
+// to DefaultLanguage when the message has no translation, and to id when it
+// is unknown.
+func Format(lang, id string, params map[string]interface{}) string {
+	template, ok := template(lang, id)
+	if !ok {
+		return id
+	}
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		n := strings.IndexByte(template[start+1:], '}')
+		if start < 0 || n < 0 {
+			b.WriteString(template)
+			return b.String()
+		}
+		end := start + 1 + n
+		param, ok := params[template[start+1:end]]
+		if !ok {
+			b.WriteString(template[:end+1])
+		} else {
+			b.WriteString(template[:start])
+			b.WriteString(formatParam(lang, param))
+		}
+		template = template[end+1:]
+	}
+}
+
+// formatParam formats a param of a message, messages being localized.
+func formatParam(lang string, v interface{}) string {
+	switch v := v.(type) {
+	case *Message:
+		return v.Localize(lang)
+	case []string:
+		return strings.Join(v, ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+// NegotiateLanguage returns the registered language best matching the
+// Accept-Language header of r, comparing the primary subtags when no tag
+// matches exactly. It returns DefaultLanguage when none matches.
+func NegotiateLanguage(r *http.Request) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, q := part, 1.0
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			tag = part[:i]
+			param := strings.TrimSpace(part[i+1:])
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			var err error
+			if q, err = strconv.ParseFloat(param[2:], 64); err != nil {
+				continue
+			}
+		}
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	for _, t := range tags {
+		if t.tag == "*" {
+			return DefaultLanguage
+		}
+		if lang, ok := languages[t.tag]; ok {
+			return lang
+		}
+		if i := strings.IndexByte(t.tag, '-'); i >= 0 {
+			if lang, ok := languages[t.tag[:i]]; ok {
+				return lang
+			}
+		}
+	}
+	return DefaultLanguage
+}
+
+// Message is a message of the catalog, identified by ID and formatted with
+// Params, see RegisterMessages. It implements error with its text in
+// DefaultLanguage, so that the reason of a ParamError can be localized.
+// Nested into the params of another message, it is encoded in JSON as its
+// code and params.
+type Message struct {
+	ID string `json:"-"`
+	// Code is the stable code of the message, see ParamError.
+	Code   string                 `json:"code,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// Error implements error interface
+func (m *Message) Error() string {
+	return m.Localize(DefaultLanguage)
+}
+
+// Localize returns the text of the message in lang.
+func (m *Message) Localize(lang string) string {
+	return Format(lang, m.ID, m.Params)
+}
+
+// Localizer is implemented by the problems whose title and reasons can be
+// localized.
+type Localizer interface {
+	// Localize returns a copy of the problem in lang.
+	Localize(lang string) Problem
+}
@@ -0,0 +1,91 @@
+package problem_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sganon/go-request/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	problem.RegisterMessages(problem.DefaultLanguage, map[string]string{
+		"test.between": "must be between {min} and {max}",
+		"test.one_of":  "must be one of: {expected}",
+		"test.nested":  "{field}: {reason}",
+	})
+	problem.RegisterMessages("fr", map[string]string{
+		"test.between":      "doit être entre {min} et {max}",
+		"problem.not_found": "La ressource demandée est introuvable",
+	})
+	problem.RegisterMessages("pt-BR", map[string]string{
+		"test.between": "deve estar entre {min} e {max}",
+	})
+}
+
+func TestFormat(t *testing.T) {
+	params := map[string]interface{}{"min": 1, "max": 10}
+	assert.Equal(t, "must be between 1 and 10", problem.Format("en", "test.between", params))
+	assert.Equal(t, "doit être entre 1 et 10", problem.Format("fr", "test.between", params))
+	assert.Equal(t, "deve estar entre 1 e 10", problem.Format("PT-br", "test.between", params))
+	assert.Equal(t, "must be between 1 and 10", problem.Format("de", "test.between", params), "an unknown language should fall back to English")
+	assert.Equal(t, "must be between {min} and 10", problem.Format("en", "test.between", map[string]interface{}{"max": 10}))
+	assert.Equal(t, "test.unknown", problem.Format("en", "test.unknown", nil))
+	assert.Equal(t, "must be one of: a, b", problem.Format("en", "test.one_of", map[string]interface{}{"expected": []string{"a", "b"}}))
+
+	msg := &problem.Message{ID: "test.nested", Params: map[string]interface{}{
+		"field":  "age",
+		"reason": &problem.Message{ID: "test.between", Params: params},
+	}}
+	assert.EqualError(t, msg, "age: must be between 1 and 10")
+	assert.Equal(t, "age: doit être entre 1 et 10", msg.Localize("fr"))
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: "en"},
+		{header: "fr", want: "fr"},
+		{header: "fr-CA, en;q=0.8", want: "fr"},
+		{header: "de, en;q=0.5, fr;q=0.8", want: "fr"},
+		{header: "pt-br", want: "pt-BR"},
+		{header: "pt", want: "en"},
+		{header: "fr;q=0, *", want: "en"},
+		{header: "es;q=x, fr;q=0.1", want: "fr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Accept-Language", tt.header)
+			assert.Equal(t, tt.want, problem.NegotiateLanguage(r))
+		})
+	}
+}
+
+func TestParamErrorLocalize(t *testing.T) {
+	paramErr := problem.NewParamError("age", &problem.Message{ID: "test.between", Params: map[string]interface{}{"min": 1, "max": 10}})
+	assert.Equal(t, "must be between 1 and 10", paramErr.Reason)
+	assert.Equal(t, "doit être entre 1 et 10", paramErr.Localize("fr").Reason)
+
+	paramErr = problem.ParamError{Field: "age", Reason: "too old"}
+	assert.Equal(t, paramErr, paramErr.Localize("fr"), "a reason without message should be kept")
+}
+
+func TestProblemLocalize(t *testing.T) {
+	prob := problem.DefaultNotFound.Localize("fr")
+	w := httptest.NewRecorder()
+	prob.Send(w)
+	assert.Equal(t, "fr", w.Header().Get("Content-Language"))
+	assert.Contains(t, w.Body.String(), "La ressource demandée est introuvable")
+	assert.Equal(t, "The requested resource could not be found", problem.DefaultNotFound.Title, "the default problem should be copied")
+
+	w = httptest.NewRecorder()
+	problem.DefaultNotFound.Send(w)
+	assert.Equal(t, "en", w.Header().Get("Content-Language"))
+
+	w = httptest.NewRecorder()
+	problem.Payload{Title: "Teapot", Status: 418}.Send(w)
+	assert.Empty(t, w.Header().Get("Content-Language"), "the language of a custom title is unknown")
+}
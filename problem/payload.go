@@ -11,6 +11,8 @@ var (
 	ErrInvalidParameters = errors.New("invalid parameters")
 	ErrUnexpected        = errors.New("unexpected error")
 	ErrForbidden         = errors.New("forbidden")
+	ErrNotFound          = errors.New("not found")
+	ErrPayloadTooLarge   = errors.New("payload too large")
 )
 
 // Payload represents most basic problem of an `application/problem+json` response.
@@ -18,6 +20,10 @@ type Payload struct {
 	Type   string `json:"type"`
 	Title  string `json:"title"`
 	Status int    `json:"status"`
+	// TitleID is the message ID of Title, used to localize it
+	TitleID string `json:"-"`
+	// Language is the language of the problem, sent as Content-Language
+	Language string `json:"-"`
 }
 
 // Validate provides validation and sets default values if needed
@@ -33,5 +39,23 @@ func (p *Payload) Validate() error {
 
 // Send implements Problem
 func (p Payload) Send(w http.ResponseWriter) {
-	baseSend(w, p.Status, p)
+	baseSend(w, p.Status, p, p.Language)
+}
+
+// Localize implements Localizer
+func (p Payload) Localize(lang string) Problem {
+	return p.localize(lang)
+}
+
+// localize returns a copy of p with its title in lang.
+func (p *Payload) localize(lang string) *Payload {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	if c.TitleID != "" {
+		c.Title = Format(lang, c.TitleID, nil)
+	}
+	c.Language = lang
+	return &c
 }
@@ -9,9 +9,12 @@ type Problem interface {
 	Send(http.ResponseWriter)
 }
 
-func baseSend(w http.ResponseWriter, status int, v interface{}) {
+func baseSend(w http.ResponseWriter, status int, v interface{}, lang string) {
 	encoder := json.NewEncoder(w)
 	w.Header().Set("Content-Type", "application/problem+json")
+	if lang != "" {
+		w.Header().Set("Content-Language", lang)
+	}
 	w.WriteHeader(status)
 	err := encoder.Encode(v)
 	if err != nil {
@@ -32,7 +35,7 @@ func (i Input) Send(w http.ResponseWriter) {
 	if err != nil {
 		panic(err)
 	}
-	baseSend(w, http.StatusBadRequest, i)
+	baseSend(w, http.StatusBadRequest, i, i.Language)
 }
 
 // Error implement error interface
@@ -40,10 +43,50 @@ func (i Input) Error() string {
 	return ErrInvalidParameters.Error()
 }
 
+// Localize implements Localizer, localizing the reasons of the invalid
+// parameters.
+func (i Input) Localize(lang string) Problem {
+	i.Payload = i.Payload.localize(lang)
+	params := make([]ParamError, len(i.InvalidParams))
+	for j, paramErr := range i.InvalidParams {
+		params[j] = paramErr.Localize(lang)
+	}
+	i.InvalidParams = params
+	return &i
+}
+
 // ParamError describe an error on a specific parameter
 type ParamError struct {
 	Field  string `json:"field"`
 	Reason string `json:"reason"`
+	// In is the source of the parameter, e.g query or header
+	In string `json:"in,omitempty"`
+	// Code is a stable code of the error, such as required, letting
+	// clients render their own message from it and Params.
+	Code string `json:"code,omitempty"`
+	// Params are the arguments of the message, e.g the min of a range.
+	Params map[string]interface{} `json:"params,omitempty"`
+	// MessageID identifies the message of Reason, used to localize it with
+	// Params, see Message.
+	MessageID string `json:"-"`
+}
+
+// NewParamError returns the error of field, with err as reason. The code and
+// params of a *Message are kept, and it is localized along the problem.
+func NewParamError(field string, err error) ParamError {
+	paramErr := ParamError{Field: field, Reason: err.Error()}
+	if msg, ok := err.(*Message); ok {
+		paramErr.Code, paramErr.Params, paramErr.MessageID = msg.Code, msg.Params, msg.ID
+	}
+	return paramErr
+}
+
+// Localize returns a copy of e with its reason in lang.
+func (e ParamError) Localize(lang string) ParamError {
+	if e.MessageID != "" {
+		e.Reason = Format(lang, e.MessageID, e.Params)
+	}
+	return e
 }
 
 // UnexpectedProblem problem
@@ -56,13 +99,19 @@ func (u UnexpectedProblem) Error() string {
 	return ErrUnexpected.Error()
 }
 
+// Localize implements Localizer
+func (u UnexpectedProblem) Localize(lang string) Problem {
+	u.Payload = u.Payload.localize(lang)
+	return &u
+}
+
 // Send implements Problem interface
 func (u UnexpectedProblem) Send(w http.ResponseWriter) {
 	err := u.Validate()
 	if err != nil {
 		panic(err)
 	}
-	baseSend(w, http.StatusInternalServerError, u)
+	baseSend(w, http.StatusInternalServerError, u, u.Language)
 }
 
 // ForbiddenProblem problem
@@ -75,35 +124,129 @@ func (f ForbiddenProblem) Error() string {
 	return ErrForbidden.Error()
 }
 
+// Localize implements Localizer
+func (f ForbiddenProblem) Localize(lang string) Problem {
+	f.Payload = f.Payload.localize(lang)
+	return &f
+}
+
 // Send implements Problem interface
 func (f ForbiddenProblem) Send(w http.ResponseWriter) {
 	err := f.Validate()
 	if err != nil {
 		panic(err)
 	}
-	baseSend(w, http.StatusForbidden, f)
+	baseSend(w, http.StatusForbidden, f, f.Language)
+}
+
+// NotFoundProblem problem
+type NotFoundProblem struct {
+	*Payload
+}
+
+// Error implements error interface
+func (n NotFoundProblem) Error() string {
+	return ErrNotFound.Error()
+}
+
+// Localize implements Localizer
+func (n NotFoundProblem) Localize(lang string) Problem {
+	n.Payload = n.Payload.localize(lang)
+	return &n
+}
+
+// Send implements Problem interface
+func (n NotFoundProblem) Send(w http.ResponseWriter) {
+	err := n.Validate()
+	if err != nil {
+		panic(err)
+	}
+	baseSend(w, http.StatusNotFound, n, n.Language)
+}
+
+// PayloadTooLargeProblem problem
+type PayloadTooLargeProblem struct {
+	*Payload
+}
+
+// Error implements error interface
+func (p PayloadTooLargeProblem) Error() string {
+	return ErrPayloadTooLarge.Error()
+}
+
+// Localize implements Localizer
+func (p PayloadTooLargeProblem) Localize(lang string) Problem {
+	p.Payload = p.Payload.localize(lang)
+	return &p
+}
+
+// Send implements Problem interface
+func (p PayloadTooLargeProblem) Send(w http.ResponseWriter) {
+	err := p.Validate()
+	if err != nil {
+		panic(err)
+	}
+	baseSend(w, http.StatusRequestEntityTooLarge, p, p.Language)
 }
 
 var DefaultUnexpected = UnexpectedProblem{
 	Payload: &Payload{
-		Type:   "about:blank",
-		Title:  "An unexpected error occured decoding request",
-		Status: http.StatusInternalServerError,
+		Type:     "about:blank",
+		Title:    "An unexpected error occured decoding request",
+		TitleID:  "problem.unexpected",
+		Language: DefaultLanguage,
+		Status:   http.StatusInternalServerError,
 	},
 }
 
 var DefaultInput = Input{
 	Payload: &Payload{
-		Type:   "about:blank",
-		Title:  "Your parameters didn't validate",
-		Status: http.StatusBadRequest,
+		Type:     "about:blank",
+		Title:    "Your parameters didn't validate",
+		TitleID:  "problem.input",
+		Language: DefaultLanguage,
+		Status:   http.StatusBadRequest,
 	},
 }
 
 var DefaultForbidden = ForbiddenProblem{
 	Payload: &Payload{
-		Type:   "about:blank",
-		Title:  "Your are missing credentials or have insufficient rights",
-		Status: http.StatusForbidden,
+		Type:     "about:blank",
+		Title:    "Your are missing credentials or have insufficient rights",
+		TitleID:  "problem.forbidden",
+		Language: DefaultLanguage,
+		Status:   http.StatusForbidden,
 	},
 }
+
+var DefaultNotFound = NotFoundProblem{
+	Payload: &Payload{
+		Type:     "about:blank",
+		Title:    "The requested resource could not be found",
+		TitleID:  "problem.not_found",
+		Language: DefaultLanguage,
+		Status:   http.StatusNotFound,
+	},
+}
+
+var DefaultPayloadTooLarge = PayloadTooLargeProblem{
+	Payload: &Payload{
+		Type:     "about:blank",
+		Title:    "The request body is too large",
+		TitleID:  "problem.payload_too_large",
+		Language: DefaultLanguage,
+		Status:   http.StatusRequestEntityTooLarge,
+	},
+}
+
+func init() {
+	for _, p := range []*Payload{
+		DefaultUnexpected.Payload,
+		DefaultInput.Payload,
+		DefaultForbidden.Payload,
+		DefaultNotFound.Payload,
+		DefaultPayloadTooLarge.Payload,
+	} {
+		RegisterMessages(DefaultLanguage, map[string]string{p.TitleID: p.Title})
+	}
+}
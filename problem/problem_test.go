@@ -1,6 +1,7 @@
 package problem_test
 
 import (
+	"encoding/json"
 	"net/http/httptest"
 	"testing"
 
@@ -49,3 +50,41 @@ func TestUnexpectedProblemSend(t *testing.T) {
 		prob.Send(w)
 	}, "send should panic if problem is not valid")
 }
+
+func TestNotFoundProblemSend(t *testing.T) {
+	w := httptest.NewRecorder()
+	prob := problem.DefaultNotFound
+	prob.Send(w)
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, problem.ErrNotFound.Error(), prob.Error())
+}
+
+func TestPayloadTooLargeProblemSend(t *testing.T) {
+	w := httptest.NewRecorder()
+	prob := problem.DefaultPayloadTooLarge
+	prob.Send(w)
+	assert.Equal(t, 413, w.Code)
+	assert.Equal(t, problem.ErrPayloadTooLarge.Error(), prob.Error())
+}
+
+func TestParamErrorJSON(t *testing.T) {
+	b, err := json.Marshal(problem.ParamError{Field: "age", Reason: "too old", In: "query"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"field":"age","reason":"too old","in":"query"}`, string(b), "a custom error should keep its encoding")
+
+	b, err = json.Marshal(problem.NewParamError("filter", &problem.Message{
+		ID:   "test.nested",
+		Code: problem.CodeInvalidFormat,
+		Params: map[string]interface{}{
+			"field":  "age",
+			"reason": &problem.Message{ID: "test.between", Code: problem.CodeOutOfRange, Params: map[string]interface{}{"min": 1}},
+		},
+	}))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"field": "filter",
+		"reason": "age: must be between 1 and {max}",
+		"code": "invalid_format",
+		"params": {"field": "age", "reason": {"code": "out_of_range", "params": {"min": 1}}}
+	}`, string(b))
+}
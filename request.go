@@ -1,29 +1,41 @@
 package request
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/sganon/go-request/form"
 	"github.com/sganon/go-request/problem"
+	"github.com/sganon/go-request/validate"
 )
 
 type Output interface {
 	Validate() []problem.ParamError
 }
 
-func Decode(r *http.Request, formOutput interface{}, bodyOutput interface{}) problem.Problem {
-	var ok bool
+// Decode decodes the form parameters of r onto formOutput and its JSON body
+// onto bodyOutput, both being optional. The returned problem is localized in
+// the language negotiated from the Accept-Language header of r, see
+// problem.NegotiateLanguage.
+func Decode(r *http.Request, formOutput interface{}, bodyOutput interface{}, opts ...form.Option) problem.Problem {
+	return localize(r, decode(r, formOutput, bodyOutput, opts...))
+}
+
+func decode(r *http.Request, formOutput interface{}, bodyOutput interface{}, opts ...form.Option) problem.Problem {
 	var inputProblem *problem.Input
-	var unexpectedProblem *problem.UnexpectedProblem
 	if formOutput != nil {
-		formDecoder := form.NewDecoder(r)
-		err := formDecoder.Decode(formOutput)
-		if prob, ok := err.(*problem.Input); ok && prob != nil {
-			inputProblem = prob
-		}
-		if unexpectedProblem, ok = err.(*problem.UnexpectedProblem); ok && unexpectedProblem != nil {
-			return unexpectedProblem
+		formDecoder := form.NewDecoder(r, opts...)
+		switch err := formDecoder.Decode(formOutput).(type) {
+		case *problem.Input:
+			inputProblem = err
+		case problem.Problem:
+			return err
+		case nil:
+		default:
+			// Programming errors such as an invalid form tag
+			return &problem.DefaultUnexpected
 		}
 	}
 	if bodyOutput != nil {
@@ -40,13 +52,22 @@ func Decode(r *http.Request, formOutput interface{}, bodyOutput interface{}) pro
 	return inputProblem
 }
 
-func DecodeAndValidate(r *http.Request, formOutput Output, bodyOutput Output) problem.Problem {
+// DecodeAndValidate decodes r as Decode, then validates the outputs. The
+// errors of Validate are localized along the problem when they are built
+// with problem.NewParamError from a *problem.Message.
+func DecodeAndValidate(r *http.Request, formOutput Output, bodyOutput Output, opts ...form.Option) problem.Problem {
+	return localize(r, decodeAndValidate(r, formOutput, bodyOutput, opts...))
+}
+
+func decodeAndValidate(r *http.Request, formOutput Output, bodyOutput Output, opts ...form.Option) problem.Problem {
 	var inputProblem *problem.Input
-	p := Decode(r, formOutput, bodyOutput)
-	if prob, ok := p.(*problem.Input); ok {
+	p := decode(r, formOutput, bodyOutput, opts...)
+	if p != nil {
+		prob, ok := p.(*problem.Input)
+		if !ok {
+			return p
+		}
 		inputProblem = prob
-	} else {
-		return prob
 	}
 	if formOutput != nil {
 		formErrors := formOutput.Validate()
@@ -59,7 +80,12 @@ func DecodeAndValidate(r *http.Request, formOutput Output, bodyOutput Output) pr
 		}
 	}
 	if bodyOutput != nil {
-		bodyErrors := bodyOutput.Validate()
+		// Rules of validate tags are checked by the form decoder for the form
+		bodyErrors, err := validate.Struct(bodyOutput)
+		if err != nil {
+			return &problem.DefaultUnexpected
+		}
+		bodyErrors = append(bodyErrors, bodyOutput.Validate()...)
 		if len(bodyErrors) > 0 {
 			if inputProblem == nil {
 				prob := problem.DefaultInput
@@ -73,3 +99,39 @@ func DecodeAndValidate(r *http.Request, formOutput Output, bodyOutput Output) pr
 	}
 	return inputProblem
 }
+
+// localize returns p in the language negotiated from r, if it is a
+// problem.Localizer.
+func localize(r *http.Request, p problem.Problem) problem.Problem {
+	if l, ok := p.(problem.Localizer); ok {
+		return l.Localize(problem.NegotiateLanguage(r))
+	}
+	return p
+}
+
+// NewRequest builds a request to target from the same inputs as Decode:
+// formInput is encoded with form.Encoder.EncodeRequest, see its tags, and
+// bodyInput is marshalled as a JSON body. Both are optional.
+func NewRequest(method, target string, formInput interface{}, bodyInput interface{}) (*http.Request, error) {
+	var body io.Reader
+	if bodyInput != nil {
+		b, err := json.Marshal(bodyInput)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	r, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if bodyInput != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if formInput != nil {
+		if err := form.NewEncoder().EncodeRequest(r, formInput); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	request "github.com/sganon/go-request"
+	"github.com/sganon/go-request/form"
 	"github.com/sganon/go-request/problem"
 	"github.com/stretchr/testify/assert"
 )
@@ -128,3 +129,134 @@ var handlerFunc = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request)
 		return
 	}
 })
+
+type invalidDefaultQuery struct {
+	Limit int `form:"limit,default=twenty"`
+}
+
+func TestDecodeTagError(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	var form invalidDefaultQuery
+	prob := request.Decode(req, &form, nil)
+	assert.IsType(t, &problem.UnexpectedProblem{}, prob, "an invalid tag should be an unexpected problem")
+}
+
+type rulesBody struct {
+	Name  string `json:"name" validate:"required,maxlen=5"`
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
+func (b rulesBody) Validate() (errs []problem.ParamError) {
+	return nil
+}
+
+func TestDecodeAndValidateRules(t *testing.T) {
+	req := httptest.NewRequest("POST", "/?foo=bar", bytes.NewBufferString(`{"name": "too long", "email": "nope"}`))
+	var form inputQuery
+	var body rulesBody
+	prob := request.DecodeAndValidate(req, &form, &body)
+	if assert.IsType(t, &problem.Input{}, prob) {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "name", Reason: "length must be at most 5", Code: "length", MessageID: "validate.maxlen", Params: map[string]interface{}{"max": 5}},
+			{Field: "email", Reason: "must be a valid email address", Code: "invalid_format", MessageID: "validate.email", Params: map[string]interface{}{"format": "email"}},
+		}, prob.(*problem.Input).InvalidParams)
+	}
+
+	req = httptest.NewRequest("POST", "/?foo=bar", bytes.NewBufferString(`{"name": "bob"}`))
+	body = rulesBody{}
+	prob = request.DecodeAndValidate(req, &form, &body)
+	assert.Nil(t, prob)
+}
+
+type pathQuery struct {
+	ID int `path:"id"`
+}
+
+func TestDecodePathOptions(t *testing.T) {
+	vars := form.PathVarsFunc(func(*http.Request) map[string]string { return map[string]string{"id": "abc"} })
+	req := httptest.NewRequest("GET", "/items/abc", nil)
+	var query pathQuery
+	prob := request.Decode(req, &query, nil, form.WithPathParams(vars))
+	assert.IsType(t, &problem.Input{}, prob)
+
+	prob = request.Decode(req, &query, nil, form.WithPathParams(vars), form.WithPathErrorStatus(http.StatusNotFound))
+	assert.IsType(t, &problem.NotFoundProblem{}, prob)
+}
+
+func TestNewRequest(t *testing.T) {
+	type itemQuery struct {
+		ID    string `path:"id"`
+		Limit int    `query:"limit,omitempty"`
+		Token string `header:"x-token"`
+	}
+	req, err := request.NewRequest("POST", "http://localhost/items/{id}", itemQuery{ID: "abc", Limit: 10, Token: "secret"}, inputBody{Foo: "baz"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "/items/abc", req.URL.Path)
+	assert.Equal(t, "limit=10", req.URL.RawQuery)
+	assert.Equal(t, "secret", req.Header.Get("X-Token"))
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	var body inputBody
+	assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+	assert.Equal(t, "baz", body.Foo)
+
+	_, err = request.NewRequest("POST", "http://localhost/", struct {
+		Name string `postform:"name"`
+	}{Name: "foo"}, inputBody{})
+	assert.Error(t, err, "a JSON body and postform fields are exclusive")
+}
+
+type localizedBody struct {
+	Name string `json:"name" validate:"maxlen=5"`
+}
+
+func (b localizedBody) Validate() (errs []problem.ParamError) {
+	if b.Name == "admin" {
+		errs = append(errs, problem.NewParamError("name", &problem.Message{ID: "user.reserved", Params: map[string]interface{}{"name": b.Name}}))
+	}
+	return errs
+}
+
+func TestDecodeLocalized(t *testing.T) {
+	problem.RegisterMessages(problem.DefaultLanguage, map[string]string{"user.reserved": "{name} is reserved"})
+	problem.RegisterMessages("fr", map[string]string{
+		"problem.input":   "Vos paramètres sont invalides",
+		"validate.maxlen": "la longueur doit être au plus {max}",
+		"user.reserved":   "{name} est réservé",
+	})
+
+	req := httptest.NewRequest("POST", "/?foo=bar", bytes.NewBufferString(`{"name": "too long"}`))
+	req.Header.Set("Accept-Language", "de-CH, fr-CH;q=0.9, en;q=0.8")
+	var form inputQuery
+	var body localizedBody
+	prob := request.DecodeAndValidate(req, &form, &body)
+	if assert.IsType(t, &problem.Input{}, prob) {
+		input := prob.(*problem.Input)
+		assert.Equal(t, "Vos paramètres sont invalides", input.Title)
+		assert.Equal(t, "la longueur doit être au plus 5", input.InvalidParams[0].Reason)
+
+		w := httptest.NewRecorder()
+		prob.Send(w)
+		assert.Equal(t, "fr", w.Header().Get("Content-Language"))
+	}
+	assert.Equal(t, "Your parameters didn't validate", problem.DefaultInput.Title, "the default problem should not be localized")
+
+	req = httptest.NewRequest("POST", "/?foo=bar", bytes.NewBufferString(`{"name": "admin"}`))
+	req.Header.Set("Accept-Language", "fr")
+	prob = request.DecodeAndValidate(req, &form, &body)
+	if assert.IsType(t, &problem.Input{}, prob) {
+		assert.Equal(t, []problem.ParamError{
+			{Field: "name", Reason: "admin est réservé", MessageID: "user.reserved", Params: map[string]interface{}{"name": "admin"}},
+		}, prob.(*problem.Input).InvalidParams)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "fr")
+	prob = request.Decode(req, &form, nil)
+	if assert.IsType(t, &problem.Input{}, prob) {
+		input := prob.(*problem.Input)
+		assert.Equal(t, "Your form parameters could not be decoded", input.Title, "a missing translation should fall back to English")
+		assert.Equal(t, "fr", input.Language)
+	}
+}
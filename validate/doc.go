@@ -0,0 +1,12 @@
+/*
+Package validate provides declarative validation rules set in a validate struct tag,
+e.g `validate:"min=1,max=100"`. Rules are run by the form decoder on decoded parameters
+and by request.DecodeAndValidate on JSON bodies, each failing rule giving a problem.ParamError.
+
+Builtin rules are required, omitempty, min, max, len, minlen, maxlen, pattern, oneof,
+email, uuid, url and ip. Custom rules can be added with Register.
+
+The reasons of the builtin rules are *problem.Message with IDs such as validate.min, which
+can be translated with problem.RegisterMessages. A custom rule can return one as well.
+*/
+package validate
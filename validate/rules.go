@@ -0,0 +1,265 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/sganon/go-request/problem"
+)
+
+// messages are the templates of the rules messages in problem.DefaultLanguage.
+var messages = map[string]string{
+	"validate.required": "parameter is required",
+	"validate.min":      "must be greater than or equal to {min}",
+	"validate.max":      "must be less than or equal to {max}",
+	"validate.len":      "length must be {len}",
+	"validate.minlen":   "length must be at least {min}",
+	"validate.maxlen":   "length must be at most {max}",
+	"validate.pattern":  "must match pattern {pattern}",
+	"validate.oneof":    "must be one of: {expected}",
+	"validate.email":    "must be a valid email address",
+	"validate.uuid":     "must be a valid UUID",
+	"validate.url":      "must be a valid URL",
+	"validate.ip":       "must be a valid IP address",
+}
+
+var errRequired = &problem.Message{ID: "validate.required", Code: problem.CodeRequired}
+
+var errNaNBound = errors.New("NaN cannot be a bound")
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// patterns caches the compiled expressions of the pattern rule
+var patterns sync.Map
+
+func init() {
+	problem.RegisterMessages(problem.DefaultLanguage, messages)
+	rules["required"] = required
+	rules["omitempty"] = func(reflect.Value, string) error { return nil }
+	rules["min"] = compare("min", "min", func(c int) bool { return c >= 0 })
+	rules["max"] = compare("max", "max", func(c int) bool { return c <= 0 })
+	rules["len"] = length("len", "len", func(l, n int) bool { return l == n })
+	rules["minlen"] = length("minlen", "min", func(l, n int) bool { return l >= n })
+	rules["maxlen"] = length("maxlen", "max", func(l, n int) bool { return l <= n })
+	rules["pattern"] = pattern
+	rules["oneof"] = oneOf
+	rules["email"] = str("email", isEmail)
+	rules["uuid"] = str("uuid", uuidPattern.MatchString)
+	rules["url"] = str("url", isURL)
+	rules["ip"] = str("ip", func(s string) bool { return net.ParseIP(s) != nil })
+}
+
+// params checks the parameters of the builtin rules when they are parsed, the
+// ones depending on the type of the value being checked by Rules.Check.
+var params = map[string]func(string) error{
+	"min":     parseBound,
+	"max":     parseBound,
+	"len":     parseLength,
+	"minlen":  parseLength,
+	"maxlen":  parseLength,
+	"pattern": compilePattern,
+}
+
+func parseBound(param string) error {
+	p, err := strconv.ParseFloat(param, 64)
+	if err == nil && math.IsNaN(p) {
+		err = errNaNBound
+	}
+	return err
+}
+
+func parseLength(param string) error {
+	_, err := strconv.Atoi(param)
+	return err
+}
+
+func compilePattern(param string) error {
+	_, err := loadPattern(param)
+	return err
+}
+
+// loadPattern returns the compiled expression of a pattern rule.
+func loadPattern(param string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(param); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	compiled, err := regexp.Compile(param)
+	if err != nil {
+		return nil, err
+	}
+	re, _ := patterns.LoadOrStore(param, compiled)
+	return re.(*regexp.Regexp), nil
+}
+
+func required(v reflect.Value, _ string) error {
+	if v.IsZero() {
+		return errRequired
+	}
+	return nil
+}
+
+// compare returns a rule comparing a number to its parameter, ok being given
+// the sign of the comparison. The parameter is reported as key in the params
+// of the message.
+func compare(name, key string, ok func(c int) bool) Rule {
+	return func(v reflect.Value, param string) error {
+		var c int
+		var bound interface{}
+		// NaN is neither greater nor less than any bound
+		var nan bool
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			p, err := strconv.ParseInt(param, 10, 64)
+			if err != nil {
+				return &InvalidRuleError{Rule: name, Err: err}
+			}
+			c, bound = compareInt(v.Int(), p), p
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			p, err := strconv.ParseUint(param, 10, 64)
+			if err != nil {
+				return &InvalidRuleError{Rule: name, Err: err}
+			}
+			c, bound = compareUint(v.Uint(), p), p
+		case reflect.Float32, reflect.Float64:
+			p, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return &InvalidRuleError{Rule: name, Err: err}
+			}
+			if math.IsNaN(p) {
+				return &InvalidRuleError{Rule: name, Err: errNaNBound}
+			}
+			c, bound, nan = compareFloat(v.Float(), p), p, math.IsNaN(v.Float())
+		default:
+			return &InvalidRuleError{Rule: name, Err: fmt.Errorf("unsupported type %s", v.Type())}
+		}
+		if nan || !ok(c) {
+			return &problem.Message{ID: "validate." + name, Code: problem.CodeOutOfRange, Params: map[string]interface{}{key: bound}}
+		}
+		return nil
+	}
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// length returns a rule checking the length of a string, in runes, or of a
+// slice or map against its parameter, reported as key in the params of the
+// message.
+func length(name, key string, ok func(l, n int) bool) Rule {
+	return func(v reflect.Value, param string) error {
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return &InvalidRuleError{Rule: name, Err: err}
+		}
+		var l int
+		switch v.Kind() {
+		case reflect.String:
+			l = utf8.RuneCountInString(v.String())
+		case reflect.Slice, reflect.Array, reflect.Map:
+			l = v.Len()
+		default:
+			return &InvalidRuleError{Rule: name, Err: fmt.Errorf("unsupported type %s", v.Type())}
+		}
+		if !ok(l, n) {
+			return &problem.Message{ID: "validate." + name, Code: problem.CodeLength, Params: map[string]interface{}{key: n}}
+		}
+		return nil
+	}
+}
+
+// str returns a rule checking a string with valid.
+func str(name string, valid func(string) bool) Rule {
+	return func(v reflect.Value, _ string) error {
+		if v.Kind() != reflect.String {
+			return &InvalidRuleError{Rule: name, Err: fmt.Errorf("unsupported type %s", v.Type())}
+		}
+		if !valid(v.String()) {
+			return &problem.Message{ID: "validate." + name, Code: problem.CodeInvalidFormat, Params: map[string]interface{}{"format": name}}
+		}
+		return nil
+	}
+}
+
+func pattern(v reflect.Value, param string) error {
+	re, err := loadPattern(param)
+	if err != nil {
+		return &InvalidRuleError{Rule: "pattern", Err: err}
+	}
+	if v.Kind() != reflect.String {
+		return &InvalidRuleError{Rule: "pattern", Err: fmt.Errorf("unsupported type %s", v.Type())}
+	}
+	if !re.MatchString(v.String()) {
+		return &problem.Message{ID: "validate.pattern", Code: problem.CodePattern, Params: map[string]interface{}{"pattern": param}}
+	}
+	return nil
+}
+
+// oneOf checks the value is one of the space separated values of param.
+func oneOf(v reflect.Value, param string) error {
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s = strconv.FormatUint(v.Uint(), 10)
+	default:
+		return &InvalidRuleError{Rule: "oneof", Err: fmt.Errorf("unsupported type %s", v.Type())}
+	}
+	values := strings.Fields(param)
+	for _, value := range values {
+		if s == value {
+			return nil
+		}
+	}
+	return &problem.Message{ID: "validate.oneof", Code: problem.CodeInvalidValue, Params: map[string]interface{}{"expected": values}}
+}
+
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	// Reject display names such as "Foo <foo@bar.com>"
+	return err == nil && addr.Address == s
+}
+
+func isURL(s string) bool {
+	u, err := url.ParseRequestURI(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
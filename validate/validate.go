@@ -0,0 +1,343 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sganon/go-request/problem"
+)
+
+// Rule checks v against param, the value following the rule name in the tag
+// (e.g 1 for min=1). The message of the returned error is used as the reason
+// of the parameter error, unless it is an *InvalidRuleError.
+type Rule func(v reflect.Value, param string) error
+
+// InvalidRuleError reports a rule which cannot be applied, because of its
+// parameter or of the type of the field. It is a programming error.
+type InvalidRuleError struct {
+	Rule string
+	Err  error
+}
+
+// Error implements error interface
+func (e *InvalidRuleError) Error() string {
+	return fmt.Sprintf("validate: invalid rule %s: %v", e.Rule, e.Err)
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]Rule{}
+)
+
+// lengthRules apply to a slice or a map as a whole, other rules apply to its
+// elements.
+var lengthRules = map[string]bool{
+	"required": true,
+	"len":      true,
+	"minlen":   true,
+	"maxlen":   true,
+}
+
+// Register adds the rule name, replacing an existing one.
+func Register(name string, rule Rule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules[name] = rule
+	delete(params, name)
+}
+
+// checkParam checks the parameter of a builtin rule.
+func checkParam(name, param string) error {
+	rulesMu.RLock()
+	check, ok := params[name]
+	rulesMu.RUnlock()
+	if !ok {
+		return nil
+	}
+	if err := check(param); err != nil {
+		return &InvalidRuleError{Rule: name, Err: err}
+	}
+	return nil
+}
+
+func lookup(name string) (Rule, bool) {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	rule, ok := rules[name]
+	return rule, ok
+}
+
+type rule struct {
+	name  string
+	param string
+	fn    Rule
+}
+
+// Rules are the parsed rules of a validate tag.
+type Rules []rule
+
+// Parse parses the rules of a validate tag. As rules are comma separated, a
+// part following a pattern which is neither a rule nor a name=param pair is
+// joined back to the pattern, so that patterns such as ^\d{1,3}$ can be used.
+// The parameters of the builtin rules are checked, e.g min=abc or pattern=[a
+// give an *InvalidRuleError.
+func Parse(tag string) (Rules, error) {
+	if tag == "" {
+		return nil, nil
+	}
+	var rs Rules
+	for _, part := range strings.Split(tag, ",") {
+		name, param := part, ""
+		i := strings.Index(part, "=")
+		if i >= 0 {
+			name, param = part[:i], part[i+1:]
+		}
+		fn, ok := lookup(name)
+		if !ok && i < 0 && len(rs) > 0 && rs[len(rs)-1].name == "pattern" {
+			rs[len(rs)-1].param += "," + part
+			continue
+		}
+		if !ok {
+			return nil, &InvalidRuleError{Rule: name, Err: errors.New("unknown rule")}
+		}
+		rs = append(rs, rule{name: name, param: param, fn: fn})
+	}
+	for _, r := range rs {
+		if err := checkParam(r.name, r.param); err != nil {
+			return nil, err
+		}
+	}
+	return rs, nil
+}
+
+// Validate checks v against the rules, the parameter errors being reported
+// on field. A nil pointer is only checked by required, and the elements of a
+// slice or the values of a map are checked one by one except by the length rules.
+func (rs Rules) Validate(field string, v reflect.Value) ([]problem.ParamError, error) {
+	var errs []problem.ParamError
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if rs.Has("required") {
+				errs = append(errs, problem.NewParamError(field, errRequired))
+			}
+			return errs, nil
+		}
+		v = v.Elem()
+	}
+	if rs.Has("omitempty") && v.IsZero() {
+		return nil, nil
+	}
+
+	elements := isCollection(v)
+	for _, r := range rs {
+		if elements && !lengthRules[r.name] {
+			continue
+		}
+		err := r.fn(v, r.param)
+		if _, ok := err.(*InvalidRuleError); ok {
+			return nil, err
+		} else if err != nil {
+			errs = append(errs, problem.NewParamError(field, err))
+		}
+	}
+	if !elements {
+		return errs, nil
+	}
+	elemRules := rs.elementRules()
+	if len(elemRules) == 0 {
+		return errs, nil
+	}
+	if v.Kind() == reflect.Map {
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			elemErrs, err := elemRules.Validate(fmt.Sprintf("%s[%v]", field, k), v.MapIndex(k))
+			if err != nil {
+				return nil, err
+			}
+			errs = append(errs, elemErrs...)
+		}
+		return errs, nil
+	}
+	for i := 0; i < v.Len(); i++ {
+		elemErrs, err := elemRules.Validate(fmt.Sprintf("%s[%d]", field, i), v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, elemErrs...)
+	}
+	return errs, nil
+}
+
+// elementRules returns the rules applying to the elements of a collection.
+func (rs Rules) elementRules() Rules {
+	var elemRules Rules
+	for _, r := range rs {
+		if !lengthRules[r.name] {
+			elemRules = append(elemRules, r)
+		}
+	}
+	return elemRules
+}
+
+// Check checks the rules apply to the values of type t, so that rules such as
+// email on an int are reported before any value is validated. Rules are
+// applied to the zero value of t, an *InvalidRuleError being returned.
+func (rs Rules) Check(t reflect.Type) error {
+	return rs.check(t, map[reflect.Type]bool{})
+}
+
+// check checks t, seen holding the collection types being checked to stop on
+// recursive ones.
+func (rs Rules) check(t reflect.Type, seen map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if len(rs) == 0 || t.Kind() == reflect.Interface || seen[t] {
+		return nil
+	}
+	v := reflect.Zero(t)
+	elements := isCollection(v)
+	for _, r := range rs {
+		if elements && !lengthRules[r.name] {
+			continue
+		}
+		if err, ok := r.fn(v, r.param).(*InvalidRuleError); ok {
+			return err
+		}
+	}
+	if !elements {
+		return nil
+	}
+	seen[t] = true
+	return rs.elementRules().check(t.Elem(), seen)
+}
+
+// Has reports whether the rule name is part of rs.
+func (rs Rules) Has(name string) bool {
+	for _, r := range rs {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Without returns rs without the rule name.
+func (rs Rules) Without(name string) Rules {
+	var without Rules
+	for _, r := range rs {
+		if r.name != name {
+			without = append(without, r)
+		}
+	}
+	return without
+}
+
+// Struct checks the fields of the struct pointed to by v against their
+// validate tags. Fields are named after their json tag, nested structs and
+// slices of structs being checked recursively. As with encoding/json, the
+// fields of embedded structs without json name are promoted.
+func Struct(v interface{}) ([]problem.ParamError, error) {
+	return validateStruct(reflect.Indirect(reflect.ValueOf(v)), "")
+}
+
+func validateStruct(v reflect.Value, prefix string) ([]problem.ParamError, error) {
+	if v.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	var errs []problem.ParamError
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if isEmbedded(sf) {
+			embeddedErrs, err := validateStruct(reflect.Indirect(v.Field(i)), prefix)
+			if err != nil {
+				return nil, err
+			}
+			errs = append(errs, embeddedErrs...)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := jsonName(sf)
+		if name == "-" {
+			continue
+		}
+		field := name
+		if prefix != "" {
+			field = prefix + "." + name
+		}
+		rs, err := Parse(sf.Tag.Get("validate"))
+		if err != nil {
+			return nil, err
+		}
+		fieldErrs, err := rs.Validate(field, v.Field(i))
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, fieldErrs...)
+
+		nestedErrs, err := validateNested(v.Field(i), field)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, nestedErrs...)
+	}
+	return errs, nil
+}
+
+// validateNested checks the structs held by v, if any.
+func validateNested(v reflect.Value, field string) ([]problem.ParamError, error) {
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.Struct:
+		return validateStruct(v, field)
+	case reflect.Slice, reflect.Array:
+		var errs []problem.ParamError
+		for i := 0; i < v.Len(); i++ {
+			elemErrs, err := validateNested(v.Index(i), fmt.Sprintf("%s[%d]", field, i))
+			if err != nil {
+				return nil, err
+			}
+			errs = append(errs, elemErrs...)
+		}
+		return errs, nil
+	}
+	return nil, nil
+}
+
+// isEmbedded reports whether the fields of sf are promoted, that is whether
+// sf is an embedded struct, or pointer to struct, without json name.
+func isEmbedded(sf reflect.StructField) bool {
+	if !sf.Anonymous || strings.Split(sf.Tag.Get("json"), ",")[0] != "" {
+		return false
+	}
+	t := sf.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+func jsonName(sf reflect.StructField) string {
+	tag := sf.Tag.Get("json")
+	if i := strings.Index(tag, ","); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "" {
+		return sf.Name
+	}
+	return tag
+}
+
+func isCollection(v reflect.Value) bool {
+	if v.Kind() == reflect.Map {
+		return true
+	}
+	return (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8
+}
@@ -0,0 +1,194 @@
+package validate_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sganon/go-request/problem"
+	"github.com/sganon/go-request/validate"
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type body struct {
+	Age       int       `json:"age" validate:"min=18,max=130"`
+	Name      string    `json:"name" validate:"minlen=2,maxlen=5"`
+	Code      string    `json:"code" validate:"len=3,pattern=^[A-Z]{1,3}$"`
+	Status    string    `json:"status" validate:"oneof=active pending"`
+	Email     string    `json:"email" validate:"omitempty,email"`
+	ID        string    `json:"id" validate:"uuid"`
+	Website   string    `json:"website" validate:"url"`
+	IP        string    `json:"ip" validate:"ip"`
+	Ratio     *float64  `json:"ratio" validate:"max=1"`
+	Tags      []string  `json:"tags" validate:"maxlen=2,minlen=2"`
+	Addresses []address `json:"addresses"`
+	Even      int       `json:"even" validate:"even"`
+}
+
+func TestStruct(t *testing.T) {
+	validate.Register("even", func(v reflect.Value, _ string) error {
+		if v.Int()%2 != 0 {
+			return errors.New("must be even")
+		}
+		return nil
+	})
+
+	ratio := 0.5
+	valid := body{
+		Age:       18,
+		Name:      "bob",
+		Code:      "ABC",
+		Status:    "active",
+		ID:        "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		Website:   "https://example.com/foo",
+		IP:        "::1",
+		Ratio:     &ratio,
+		Tags:      []string{"a", "b"},
+		Addresses: []address{{City: "Paris"}},
+	}
+	errs, err := validate.Struct(&valid)
+	assert.NoError(t, err)
+	assert.Empty(t, errs)
+
+	ratio = 2
+	invalid := body{
+		Age:       17,
+		Name:      "b",
+		Code:      "AB1",
+		Status:    "deleted",
+		Email:     "Bob <bob@example.com>",
+		ID:        "6ba7b810",
+		Website:   "example.com",
+		IP:        "1.2.3",
+		Ratio:     &ratio,
+		Tags:      []string{"a"},
+		Addresses: []address{{City: "Paris"}, {}},
+		Even:      1,
+	}
+	errs, err = validate.Struct(&invalid)
+	assert.NoError(t, err)
+	assert.Equal(t, []problem.ParamError{
+		{Field: "age", Reason: "must be greater than or equal to 18", Code: "out_of_range", MessageID: "validate.min", Params: map[string]interface{}{"min": int64(18)}},
+		{Field: "name", Reason: "length must be at least 2", Code: "length", MessageID: "validate.minlen", Params: map[string]interface{}{"min": 2}},
+		{Field: "code", Reason: "must match pattern ^[A-Z]{1,3}$", Code: "pattern", MessageID: "validate.pattern", Params: map[string]interface{}{"pattern": "^[A-Z]{1,3}$"}},
+		{Field: "status", Reason: "must be one of: active, pending", Code: "invalid_value", MessageID: "validate.oneof", Params: map[string]interface{}{"expected": []string{"active", "pending"}}},
+		{Field: "email", Reason: "must be a valid email address", Code: "invalid_format", MessageID: "validate.email", Params: map[string]interface{}{"format": "email"}},
+		{Field: "id", Reason: "must be a valid UUID", Code: "invalid_format", MessageID: "validate.uuid", Params: map[string]interface{}{"format": "uuid"}},
+		{Field: "website", Reason: "must be a valid URL", Code: "invalid_format", MessageID: "validate.url", Params: map[string]interface{}{"format": "url"}},
+		{Field: "ip", Reason: "must be a valid IP address", Code: "invalid_format", MessageID: "validate.ip", Params: map[string]interface{}{"format": "ip"}},
+		{Field: "ratio", Reason: "must be less than or equal to 1", Code: "out_of_range", MessageID: "validate.max", Params: map[string]interface{}{"max": float64(1)}},
+		{Field: "tags", Reason: "length must be at least 2", Code: "length", MessageID: "validate.minlen", Params: map[string]interface{}{"min": 2}},
+		{Field: "addresses[1].city", Reason: "parameter is required", Code: "required", MessageID: "validate.required"},
+		{Field: "even", Reason: "must be even"},
+	}, errs)
+}
+
+type Audit struct {
+	Author string `json:"author" validate:"required"`
+}
+
+type embeddingBody struct {
+	address
+	*Audit
+	Named address `json:"named"`
+}
+
+func TestStructEmbedded(t *testing.T) {
+	errs, err := validate.Struct(&embeddingBody{Audit: &Audit{}})
+	assert.NoError(t, err)
+	assert.Equal(t, []problem.ParamError{
+		{Field: "city", Reason: "parameter is required", Code: "required", MessageID: "validate.required"},
+		{Field: "author", Reason: "parameter is required", Code: "required", MessageID: "validate.required"},
+		{Field: "named.city", Reason: "parameter is required", Code: "required", MessageID: "validate.required"},
+	}, errs, "the fields of embedded structs should be promoted")
+
+	errs, err = validate.Struct(&embeddingBody{address: address{City: "Paris"}, Named: address{City: "Paris"}})
+	assert.NoError(t, err)
+	assert.Empty(t, errs, "a nil embedded pointer should not be checked")
+}
+
+func TestRulesValidateSlice(t *testing.T) {
+	rules, err := validate.Parse("maxlen=2,min=1")
+	assert.NoError(t, err)
+	errs, err := rules.Validate("ids", reflect.ValueOf([]int{0, 1, 2}))
+	assert.NoError(t, err)
+	assert.Equal(t, []problem.ParamError{
+		{Field: "ids", Reason: "length must be at most 2", Code: "length", MessageID: "validate.maxlen", Params: map[string]interface{}{"max": 2}},
+		{Field: "ids[0]", Reason: "must be greater than or equal to 1", Code: "out_of_range", MessageID: "validate.min", Params: map[string]interface{}{"min": int64(1)}},
+	}, errs)
+}
+
+func TestRulesValidateMap(t *testing.T) {
+	rules, err := validate.Parse("maxlen=2,min=1")
+	assert.NoError(t, err)
+	errs, err := rules.Validate("meta", reflect.ValueOf(map[string]int{"b": 0, "a": 0, "c": 1}))
+	assert.NoError(t, err)
+	assert.Equal(t, []problem.ParamError{
+		{Field: "meta", Reason: "length must be at most 2", Code: "length", MessageID: "validate.maxlen", Params: map[string]interface{}{"max": 2}},
+		{Field: "meta[a]", Reason: "must be greater than or equal to 1", Code: "out_of_range", MessageID: "validate.min", Params: map[string]interface{}{"min": int64(1)}},
+		{Field: "meta[b]", Reason: "must be greater than or equal to 1", Code: "out_of_range", MessageID: "validate.min", Params: map[string]interface{}{"min": int64(1)}},
+	}, errs)
+}
+
+func TestRulesValidateNaN(t *testing.T) {
+	rules, err := validate.Parse("min=1,max=10")
+	assert.NoError(t, err)
+	errs, err := rules.Validate("price", reflect.ValueOf(math.NaN()))
+	assert.NoError(t, err)
+	assert.Equal(t, []problem.ParamError{
+		{Field: "price", Reason: "must be greater than or equal to 1", Code: "out_of_range", MessageID: "validate.min", Params: map[string]interface{}{"min": float64(1)}},
+		{Field: "price", Reason: "must be less than or equal to 10", Code: "out_of_range", MessageID: "validate.max", Params: map[string]interface{}{"max": float64(10)}},
+	}, errs, "NaN should be out of any range")
+
+	_, err = validate.Parse("max=NaN")
+	assert.IsType(t, &validate.InvalidRuleError{}, err)
+}
+
+func TestInvalidRules(t *testing.T) {
+	_, err := validate.Parse("min=1,unknown")
+	assert.IsType(t, &validate.InvalidRuleError{}, err)
+
+	rules, err := validate.Parse("min=1")
+	assert.NoError(t, err)
+	_, err = rules.Validate("name", reflect.ValueOf("foo"))
+	assert.IsType(t, &validate.InvalidRuleError{}, err, "min should not apply to strings")
+
+	for _, tag := range []string{"min=abc", "max=", "len=1.5", "maxlen=two", "pattern=[a-", `pattern=^\d{1,3}$,pattern=(`} {
+		_, err = validate.Parse(tag)
+		assert.IsType(t, &validate.InvalidRuleError{}, err, tag)
+	}
+}
+
+func TestRulesCheck(t *testing.T) {
+	tests := []struct {
+		tag   string
+		value interface{}
+		valid bool
+	}{
+		{tag: "min=1,max=10", value: 0, valid: true},
+		{tag: "min=1.5", value: 0.0, valid: true},
+		{tag: "min=1.5", value: 0},
+		{tag: "min=-1", value: uint(0)},
+		{tag: "email", value: 0},
+		{tag: "pattern=^a", value: new(string), valid: true},
+		{tag: "maxlen=2,uuid", value: []string{}, valid: true},
+		{tag: "maxlen=2,min=1", value: map[string]*int{}, valid: true},
+		{tag: "maxlen=2,oneof=a b", value: [][]float64{}},
+		{tag: "len=2", value: 0},
+	}
+	for _, tt := range tests {
+		rules, err := validate.Parse(tt.tag)
+		assert.NoError(t, err, tt.tag)
+		err = rules.Check(reflect.TypeOf(tt.value))
+		if tt.valid {
+			assert.NoError(t, err, tt.tag)
+		} else {
+			assert.IsType(t, &validate.InvalidRuleError{}, err, tt.tag)
+		}
+	}
+}